@@ -0,0 +1,312 @@
+// Package aggregate folds the proofs of a sequence of edits into a single proof, so that a
+// verifier checks one proof for the whole lineage of a credential, from its issuance to its
+// current version.
+//
+// The edit proofs are Groth16 proofs on BLS12-377, the edit circuit being compiled on its
+// scalar field; LineageCircuit verifies them inside a BW6-761 circuit with gnark's
+// std/groth16_bls12377 and chains their public histories, see circuit.CheckHistory.
+//
+// The PhdEditCircuit of package phd is proved on BN254, which has no curve to verify its proofs
+// in a circuit. VerifyLineage checks its lineages instead: it chains the histories natively and
+// verifies all the proofs with a single pairing product, see package batch, the verifier
+// receiving every proof rather than one.
+package aggregate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377"
+	"github.com/consensys/gnark-crypto/ecc/bls12-377/fp"
+	"github.com/consensys/gnark-crypto/ecc/bls12-377/fr"
+	bn254fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	bw6fr "github.com/consensys/gnark-crypto/ecc/bw6-761/fr"
+	"github.com/consensys/gnark-crypto/hash"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/schema"
+	"github.com/consensys/gnark/std/algebra/sw_bls12377"
+	"github.com/consensys/gnark/std/groth16_bls12377"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+// Layout locates the histories among the public inputs of an edit circuit
+type Layout struct {
+	NbPublic   int
+	OldVersion int
+	OldDigest  int
+	OldIssued  int
+	NewVersion int
+	NewDigest  int
+	NewIssued  int
+}
+
+var tVariable = reflect.TypeOf((*frontend.Variable)(nil)).Elem()
+
+// NewLayout returns the layout of edit, whose public OldHistory and NewHistory fields are
+// circuit.History. Slices of edit must be allocated as for compiling it.
+func NewLayout(edit frontend.Circuit) (Layout, error) {
+	index := map[string]int{}
+	count, err := schema.Walk(edit, tVariable, func(f schema.LeafInfo, _ reflect.Value) error {
+		if f.Visibility == schema.Public {
+			index[f.FullName()] = len(index)
+		}
+		return nil
+	})
+	if err != nil {
+		return Layout{}, err
+	}
+	res := Layout{NbPublic: count.Public}
+	for name, i := range map[string]*int{
+		"OldHistory_Version": &res.OldVersion,
+		"OldHistory_Digest":  &res.OldDigest,
+		"OldHistory_Issued":  &res.OldIssued,
+		"NewHistory_Version": &res.NewVersion,
+		"NewHistory_Digest":  &res.NewDigest,
+		"NewHistory_Issued":  &res.NewIssued,
+	} {
+		j, ok := index[name]
+		if !ok {
+			return Layout{}, fmt.Errorf("the edit circuit has no public %s", name)
+		}
+		*i = j
+	}
+	return res, nil
+}
+
+func (l Layout) oldHistory(public []frontend.Variable) circuit.History {
+	return circuit.History{Version: public[l.OldVersion], Digest: public[l.OldDigest], Issued: public[l.OldIssued]}
+}
+
+func (l Layout) newHistory(public []frontend.Variable) circuit.History {
+	return circuit.History{Version: public[l.NewVersion], Digest: public[l.NewDigest], Issued: public[l.NewIssued]}
+}
+
+// Histories returns the public histories an edit proof goes from and to, as *big.Int, read from
+// its public witness on BN254 or BLS12-377
+func (l Layout) Histories(public witness.Witness) (circuit.History, circuit.History, error) {
+	var inputs []frontend.Variable
+	switch v := public.Vector().(type) {
+	case fr.Vector:
+		for i := range v {
+			inputs = append(inputs, v[i].BigInt(new(big.Int)))
+		}
+	case bn254fr.Vector:
+		for i := range v {
+			inputs = append(inputs, v[i].BigInt(new(big.Int)))
+		}
+	default:
+		return circuit.History{}, circuit.History{}, errors.New("not a public witness on BN254 or BLS12-377")
+	}
+	if len(inputs) != l.NbPublic {
+		return circuit.History{}, circuit.History{}, fmt.Errorf("expected %d public inputs, got %d", l.NbPublic, len(inputs))
+	}
+	return l.oldHistory(inputs), l.newHistory(inputs), nil
+}
+
+// Step is an edit proof and its public inputs
+type Step struct {
+	Proof  groth16_bls12377.Proof
+	Public []frontend.Variable
+}
+
+// LineageCircuit proves that each of Steps is a valid edit proof starting from the history the
+// previous one ended with, from OldHistory to NewHistory. Statement is the MiMC hash of the
+// public inputs of all steps, binding their records, limits and keys, see Statement. The
+// verifying key of the edit circuit is a constant of the circuit.
+type LineageCircuit struct {
+	OldHistory circuit.History   `gnark:",public"`
+	NewHistory circuit.History   `gnark:",public"`
+	Statement  frontend.Variable `gnark:",public"`
+	Steps      []Step
+	VK         groth16_bls12377.VerifyingKey `gnark:"-"`
+	Layout     Layout                        `gnark:"-"`
+}
+
+func (c *LineageCircuit) Define(api frontend.API) error {
+	hash, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	l := c.Layout
+	history := c.OldHistory
+	for _, s := range c.Steps {
+		if len(s.Public) != l.NbPublic {
+			return errors.New("invalid number of public inputs")
+		}
+		api.AssertIsEqual(s.Public[l.OldVersion], history.Version)
+		api.AssertIsEqual(s.Public[l.OldDigest], history.Digest)
+		api.AssertIsEqual(s.Public[l.OldIssued], history.Issued)
+		verifyProof(api, c.VK, s.Proof, s.Public)
+		hash.Write(s.Public...)
+		history = l.newHistory(s.Public)
+	}
+	api.AssertIsEqual(c.NewHistory.Version, history.Version)
+	api.AssertIsEqual(c.NewHistory.Digest, history.Digest)
+	api.AssertIsEqual(c.NewHistory.Issued, history.Issued)
+	api.AssertIsEqual(c.Statement, hash.Sum())
+	return nil
+}
+
+// publicShift is added to every public input of the edit proofs before the scalar
+// multiplications of verifyProof, and subtracted from the constant point of the key
+var publicShift, _ = new(big.Int).SetString("0x1b6f9a2c4e8d03f5a7c1e9b2d4f60817", 0)
+
+// glvLambda is the cube root of unity of the scalar field of BLS12-377 with which
+// sw_bls12377 splits the scalars of its multiplications
+var glvLambda, _ = new(big.Int).SetString("0x452217cc900000010a11800000000000", 0)
+
+// mulExceptions are the scalars modulo r of BLS12-377 on which the scalar multiplication of
+// sw_bls12377 fails, its incomplete formulas adding a point to its opposite: 0, -λ and 1-λ
+var mulExceptions = []*big.Int{
+	big.NewInt(0),
+	new(big.Int).Sub(ecc.BLS12_377.ScalarField(), glvLambda),
+	new(big.Int).Sub(ecc.BLS12_377.ScalarField(), new(big.Int).Sub(glvLambda, big.NewInt(1))),
+}
+
+// verifyProof is groth16_bls12377.Verify, whose scalar multiplications use incomplete
+// formulas and fail on a zero scalar, e.g. the version of an issued record or a padding block.
+// The key being constant, shifting the public inputs costs no constraint, and addShiftedMul
+// handles the inputs the shift brings to one of mulExceptions.
+func verifyProof(api frontend.API, vk groth16_bls12377.VerifyingKey, proof groth16_bls12377.Proof, public []frontend.Variable) {
+	if len(vk.G1.K) != len(public)+1 {
+		panic("the verifying key does not match the public inputs")
+	}
+	// kSum = Kvk[0] - Σ[shift]Kvk[i] + Σ[x+shift]Kvk[i]
+	var kSum, shift sw_bls12377.G1Affine
+	shift.ScalarMul(api, vk.G1.K[1], publicShift)
+	for i := 2; i < len(vk.G1.K); i++ {
+		var ki sw_bls12377.G1Affine
+		ki.ScalarMul(api, vk.G1.K[i], publicShift)
+		shift.AddAssign(api, ki)
+	}
+	kSum.Neg(api, shift)
+	kSum.AddAssign(api, vk.G1.K[0])
+	for i, x := range public {
+		kSum = addShiftedMul(api, kSum, vk.G1.K[i+1], x)
+	}
+
+	// e(kSum, -[γ]2) * e(Krs, -[δ]2) * e(Ar, Bs) == e(α, β)
+	ml, _ := sw_bls12377.MillerLoop(api, []sw_bls12377.G1Affine{kSum, proof.Krs, proof.Ar}, []sw_bls12377.G2Affine{vk.G2.GammaNeg, vk.G2.DeltaNeg, proof.Bs})
+	pairing := sw_bls12377.FinalExponentiation(api, ml)
+	vk.E.AssertIsEqual(api, pairing)
+}
+
+// addShiftedMul returns p + [x+publicShift]q for a public input x and a constant point q of the
+// key. On the inputs whose shifted scalar is one of mulExceptions, q is multiplied by
+// publicShift instead and the result replaced by the product computed natively, the infinity
+// of the zero scalar adding nothing.
+func addShiftedMul(api frontend.API, p sw_bls12377.G1Affine, q sw_bls12377.G1Affine, x frontend.Variable) sw_bls12377.G1Affine {
+	r := ecc.BLS12_377.ScalarField()
+	native := bls12377.G1Affine{X: fp.Element(q.X.(bw6fr.Element)), Y: fp.Element(q.Y.(bw6fr.Element))}
+	scalar := api.Add(x, publicShift)
+	hits := make([]frontend.Variable, len(mulExceptions))
+	for i, e := range mulExceptions {
+		// the input shifted to e
+		input := new(big.Int).Sub(e, publicShift)
+		hits[i] = api.IsZero(api.Sub(x, input.Mod(input, r)))
+		scalar = api.Select(hits[i], publicShift, scalar)
+	}
+	var mul sw_bls12377.G1Affine
+	mul.ScalarMul(api, q, scalar)
+	for i, e := range mulExceptions[1:] {
+		var product bls12377.G1Affine
+		product.ScalarMultiplication(&native, e)
+		var constant sw_bls12377.G1Affine
+		constant.Assign(&product)
+		mul.X = api.Select(hits[i+1], constant.X, mul.X)
+		mul.Y = api.Select(hits[i+1], constant.Y, mul.Y)
+	}
+	sum := p
+	sum.AddAssign(api, mul)
+	sum.X = api.Select(hits[0], p.X, sum.X)
+	sum.Y = api.Select(hits[0], p.Y, sum.Y)
+	return sum
+}
+
+// NewLineageCircuit returns the circuit folding n edit proofs under the verifying key vk of
+// the edit circuit, to be compiled on BW6-761
+func NewLineageCircuit(vk groth16.VerifyingKey, layout Layout, n int) *LineageCircuit {
+	res := &LineageCircuit{Steps: make([]Step, n), Layout: layout}
+	res.VK.Assign(vk)
+	if len(res.VK.G1.K) != layout.NbPublic+1 {
+		panic("the verifying key does not match the layout")
+	}
+	// the point of an unconstrained input is the infinity, (0, 0) in affine coordinates, which
+	// the incomplete formulas of the scalar multiplication do not handle
+	for i, k := range res.VK.G1.K[1:] {
+		if x, y := k.X.(bw6fr.Element), k.Y.(bw6fr.Element); x.IsZero() && y.IsZero() {
+			panic(fmt.Sprintf("public input %d of the edit circuit is unconstrained", i))
+		}
+	}
+	for i := range res.Steps {
+		res.Steps[i].Public = make([]frontend.Variable, layout.NbPublic)
+	}
+	return res
+}
+
+// Assign returns the assignment of NewLineageCircuit(vk, layout, len(proofs)), publics being
+// the public witnesses of the edit proofs in the order of the lineage
+func Assign(vk groth16.VerifyingKey, layout Layout, proofs []groth16.Proof, publics []witness.Witness) (*LineageCircuit, error) {
+	if len(proofs) == 0 || len(proofs) != len(publics) {
+		return nil, errors.New("every proof needs its public witness")
+	}
+	res := NewLineageCircuit(vk, layout, len(proofs))
+	vectors := make([]fr.Vector, len(publics))
+	for i := range proofs {
+		if err := assignProof(&res.Steps[i].Proof, proofs[i]); err != nil {
+			return nil, err
+		}
+		v, ok := publics[i].Vector().(fr.Vector)
+		if !ok || len(v) != layout.NbPublic {
+			return nil, fmt.Errorf("the public witness of edit %d is not one of the edit circuit on BLS12-377", i)
+		}
+		vectors[i] = v
+		for j := range v {
+			res.Steps[i].Public[j] = v[j].BigInt(new(big.Int))
+		}
+	}
+	res.OldHistory = layout.oldHistory(res.Steps[0].Public)
+	res.NewHistory = layout.newHistory(res.Steps[len(res.Steps)-1].Public)
+	res.Statement = Statement(vectors)
+	return res, nil
+}
+
+// assignProof reads the points of a Groth16 proof on BLS12-377 from its serialization
+func assignProof(res *groth16_bls12377.Proof, proof groth16.Proof) error {
+	var buf bytes.Buffer
+	if _, err := proof.WriteRawTo(&buf); err != nil {
+		return err
+	}
+	var ar, krs bls12377.G1Affine
+	var bs bls12377.G2Affine
+	dec := bls12377.NewDecoder(&buf)
+	for _, p := range []interface{}{&ar, &bs, &krs} {
+		if err := dec.Decode(p); err != nil {
+			return fmt.Errorf("not a Groth16 proof on BLS12-377: %w", err)
+		}
+	}
+	res.Ar.Assign(&ar)
+	res.Krs.Assign(&krs)
+	res.Bs.Assign(&bs)
+	return nil
+}
+
+// Statement is the native counterpart of the Statement of LineageCircuit, computed by the
+// verifier from the public inputs of the edits
+func Statement(publics []fr.Vector) *big.Int {
+	h := hash.MIMC_BW6_761.New()
+	size := h.BlockSize()
+	for _, v := range publics {
+		for i := range v {
+			h.Write(v[i].BigInt(new(big.Int)).FillBytes(make([]byte, size)))
+		}
+	}
+	return new(big.Int).SetBytes(h.Sum(nil))
+}
@@ -0,0 +1,218 @@
+package aggregate
+
+import (
+	"math/big"
+	"testing"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/Nullus-Labs/IDEA-DAC/phd"
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/algebra/sw_bls12377"
+	"github.com/consensys/gnark/test"
+)
+
+// editCircuit stands for an edit circuit, with only its history checks
+type editCircuit struct {
+	OldRecord   circuit.Record  `gnark:",public"`
+	NewRecord   circuit.Record  `gnark:",public"`
+	OldHistory  circuit.History `gnark:",public"`
+	NewHistory  circuit.History `gnark:",public"`
+	PrevHistory frontend.Variable
+}
+
+func (c *editCircuit) Define(api frontend.API) error {
+	circuit.CheckHistory(api, c.OldHistory, c.NewHistory, c.PrevHistory, c.OldRecord, c.NewRecord)
+	// the tags are checked by the full edit circuits, every public input must be constrained
+	api.AssertIsDifferent(c.OldRecord.Tag, 0)
+	api.AssertIsDifferent(c.NewRecord.Tag, 0)
+	return nil
+}
+
+type recordCircuit struct {
+	Record circuit.Record `gnark:",public"`
+}
+
+func (c *recordCircuit) Define(api frontend.API) error {
+	return nil
+}
+
+func TestNewLayout(t *testing.T) {
+	edit := phd.InitPhdEditCircuit(3)
+	layout, err := NewLayout(&edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// after the records, the histories follow one another
+	if layout.OldVersion < 2*(phd.MaxRecLen+2) || layout.OldDigest != layout.OldVersion+1 || layout.OldIssued != layout.OldVersion+2 || layout.NewVersion != layout.OldVersion+3 || layout.NewDigest != layout.OldVersion+4 || layout.NewIssued != layout.OldVersion+5 {
+		t.Fatalf("unexpected layout %+v", layout)
+	}
+	if _, err := NewLayout(&struct{ editCircuit }{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewLayout(&recordCircuit{Record: circuit.EmptyRecord(1)}); err == nil {
+		t.Fatal("expected a circuit without history to be rejected")
+	}
+}
+
+// shiftedMulCircuit asserts that addShiftedMul(P, Q, X) is Expected
+type shiftedMulCircuit struct {
+	X        frontend.Variable
+	P        sw_bls12377.G1Affine `gnark:"-"`
+	Q        sw_bls12377.G1Affine `gnark:"-"`
+	Expected sw_bls12377.G1Affine `gnark:"-"`
+}
+
+func (c *shiftedMulCircuit) Define(api frontend.API) error {
+	res := addShiftedMul(api, c.P, c.Q, c.X)
+	api.AssertIsEqual(res.X, c.Expected.X)
+	api.AssertIsEqual(res.Y, c.Expected.Y)
+	return nil
+}
+
+func TestAddShiftedMul(t *testing.T) {
+	r := ecc.BLS12_377.ScalarField()
+	_, _, g, _ := bls12377.Generators()
+	var p, q bls12377.G1Affine
+	p.ScalarMultiplication(&g, big.NewInt(7))
+	q.ScalarMultiplication(&g, big.NewInt(123456789))
+	inputs := []*big.Int{big.NewInt(0), big.NewInt(1), new(big.Int).Sub(r, big.NewInt(1))}
+	for _, e := range mulExceptions {
+		input := new(big.Int).Sub(e, publicShift)
+		inputs = append(inputs, input.Mod(input, r))
+	}
+	for _, x := range inputs {
+		var mul bls12377.G1Affine
+		mul.ScalarMultiplication(&q, new(big.Int).Add(x, publicShift))
+		var expected bls12377.G1Jac
+		expected.FromAffine(&p)
+		expected.AddMixed(&mul)
+		var e bls12377.G1Affine
+		e.FromJacobian(&expected)
+
+		c := &shiftedMulCircuit{}
+		c.P.Assign(&p)
+		c.Q.Assign(&q)
+		c.Expected.Assign(&e)
+		assignment := *c
+		assignment.X = x
+		if err := test.IsSolved(c, &assignment, ecc.BW6_761.ScalarField()); err != nil {
+			t.Errorf("%s: %v", x, err)
+		}
+	}
+}
+
+func TestLineageCircuit(t *testing.T) {
+	const capacity = 2
+	cipher := circuit.Cipher{Curve: ecc.BLS12_377, Primitive: circuit.MimcPrimitive}
+	key := big.NewInt(42)
+	var records []*circuit.Ciphertext
+	for i, json := range []string{`{"Status":"Approved"}`, `{"Status":"Ongoing"}`, `{"Status":"Graduated"}`} {
+		record, err := cipher.Encrypt([]byte(json), key, big.NewInt(int64(i+1)), capacity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	digests := []*big.Int{big.NewInt(0)}
+	for _, r := range records {
+		digests = append(digests, cipher.ChainHistory(digests[len(digests)-1], r))
+	}
+	issued := cipher.RecordDigest(records[0].Nonce, records[0].Blocks)
+
+	edit := editCircuit{OldRecord: circuit.EmptyRecord(capacity), NewRecord: circuit.EmptyRecord(capacity)}
+	cs, err := frontend.Compile(ecc.BLS12_377.ScalarField(), r1cs.NewBuilder, &edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := groth16.Setup(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prove := func(v *big.Int, i int) (groth16.Proof, witness.Witness) {
+		assignment := editCircuit{
+			OldRecord:   records[i].Record(),
+			NewRecord:   records[i+1].Record(),
+			OldHistory:  circuit.History{Version: v, Digest: digests[i+1], Issued: issued},
+			NewHistory:  circuit.History{Version: new(big.Int).Add(v, big.NewInt(1)), Digest: digests[i+2], Issued: issued},
+			PrevHistory: digests[i],
+		}
+		full, err := frontend.NewWitness(&assignment, ecc.BLS12_377.ScalarField())
+		if err != nil {
+			t.Fatal(err)
+		}
+		public, err := full.Public()
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := groth16.Prove(cs, pk, full)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := groth16.Verify(proof, vk, public); err != nil {
+			t.Fatal(err)
+		}
+		return proof, public
+	}
+	var proofs []groth16.Proof
+	var publics []witness.Witness
+	for v := 0; v < 2; v++ {
+		proof, public := prove(big.NewInt(int64(v)), v)
+		proofs = append(proofs, proof)
+		publics = append(publics, public)
+	}
+
+	layout, err := NewLayout(&edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assignment, err := Assign(vk, layout, proofs, publics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lineage := NewLineageCircuit(vk, layout, 2)
+	if assignment.OldHistory.Version.(*big.Int).Sign() != 0 || assignment.NewHistory.Digest.(*big.Int).Cmp(digests[3]) != 0 || assignment.NewHistory.Issued.(*big.Int).Cmp(issued) != 0 {
+		t.Fatal("the lineage does not go from the issued record to the last one")
+	}
+	if err := test.IsSolved(lineage, assignment, ecc.BW6_761.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+	old, _, err := layout.Histories(publics[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.Version.(*big.Int).Cmp(big.NewInt(1)) != 0 || old.Digest.(*big.Int).Cmp(digests[2]) != 0 || old.Issued.(*big.Int).Cmp(issued) != 0 {
+		t.Fatalf("unexpected history %+v", old)
+	}
+
+	// the edits out of order
+	swapped, err := Assign(vk, layout, []groth16.Proof{proofs[1], proofs[0]}, []witness.Witness{publics[1], publics[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := test.IsSolved(lineage, swapped, ecc.BW6_761.ScalarField()); err == nil {
+		t.Fatal("expected edits out of order to be rejected")
+	}
+	// a proof of another edit
+	forged, err := Assign(vk, layout, []groth16.Proof{proofs[1], proofs[1]}, publics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := test.IsSolved(lineage, forged, ecc.BW6_761.ScalarField()); err == nil {
+		t.Fatal("expected a proof of another edit to be rejected")
+	}
+
+	// a version the shift of the public inputs brings to zero
+	proof, public := prove(new(big.Int).Sub(ecc.BLS12_377.ScalarField(), publicShift), 1)
+	shifted, err := Assign(vk, layout, []groth16.Proof{proof}, []witness.Witness{public})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := test.IsSolved(NewLineageCircuit(vk, layout, 1), shifted, ecc.BW6_761.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,47 @@
+package aggregate
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/Nullus-Labs/IDEA-DAC/batch"
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+)
+
+// VerifyLineage checks the Groth16 proofs on BN254 of consecutive edits, publics being their
+// public witnesses in the order of the lineage, and returns the histories the lineage goes from
+// and to, as *big.Int. Each edit must start from the history the previous one ended with, as
+// LineageCircuit asserts, and the proofs are checked together with batch.Verify. It is the route
+// of PhdEditCircuit, proved on BN254, whose proofs LineageCircuit cannot fold. A failing edit is
+// returned as a *batch.ProofError.
+func VerifyLineage(vk groth16.VerifyingKey, layout Layout, proofs []groth16.Proof, publics []witness.Witness) (circuit.History, circuit.History, error) {
+	if len(proofs) == 0 || len(proofs) != len(publics) {
+		return circuit.History{}, circuit.History{}, errors.New("every proof needs its public witness")
+	}
+	var from, to circuit.History
+	for i := range publics {
+		oldHistory, newHistory, err := layout.Histories(publics[i])
+		if err != nil {
+			return circuit.History{}, circuit.History{}, &batch.ProofError{Index: i, Err: err}
+		}
+		if i == 0 {
+			from = oldHistory
+		} else if !sameHistory(oldHistory, to) {
+			return circuit.History{}, circuit.History{}, &batch.ProofError{Index: i, Err: errors.New("the edit does not start from the history the previous one ended with")}
+		}
+		to = newHistory
+	}
+	if err := batch.Verify(vk, proofs, publics); err != nil {
+		return circuit.History{}, circuit.History{}, err
+	}
+	return from, to, nil
+}
+
+// sameHistory compares histories returned by Layout.Histories
+func sameHistory(a circuit.History, b circuit.History) bool {
+	return a.Version.(*big.Int).Cmp(b.Version.(*big.Int)) == 0 &&
+		a.Digest.(*big.Int).Cmp(b.Digest.(*big.Int)) == 0 &&
+		a.Issued.(*big.Int).Cmp(b.Issued.(*big.Int)) == 0
+}
@@ -0,0 +1,206 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nullus-Labs/IDEA-DAC/batch"
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/Nullus-Labs/IDEA-DAC/history"
+	"github.com/Nullus-Labs/IDEA-DAC/phd"
+	"github.com/Nullus-Labs/IDEA-DAC/policy"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/signature"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+// proveEdits proves the edits of assignments with a setup of the circuit of edit on BN254
+func proveEdits(t *testing.T, edit frontend.Circuit, assignments []frontend.Circuit) (groth16.VerifyingKey, []groth16.Proof, []witness.Witness) {
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := groth16.Setup(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var proofs []groth16.Proof
+	var publics []witness.Witness
+	for _, assignment := range assignments {
+		full, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatal(err)
+		}
+		public, err := full.Public()
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := groth16.Prove(cs, pk, full)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proofs = append(proofs, proof)
+		publics = append(publics, public)
+	}
+	return vk, proofs, publics
+}
+
+func TestVerifyLineage(t *testing.T) {
+	const capacity = 2
+	cipher := circuit.Cipher{Curve: ecc.BN254, Primitive: circuit.MimcPrimitive}
+	key := big.NewInt(42)
+	var lineage *history.Store
+	var assignments []frontend.Circuit
+	for i, json := range []string{`{"Status":"Approved"}`, `{"Status":"Ongoing"}`, `{"Status":"Graduated"}`} {
+		record, err := cipher.Encrypt([]byte(json), key, big.NewInt(int64(i+1)), capacity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lineage == nil {
+			lineage = history.New(cipher, record)
+			continue
+		}
+		head, next := lineage.Head(), lineage.Next(record)
+		assignments = append(assignments, &editCircuit{
+			OldRecord:   head.Record.Record(),
+			NewRecord:   record.Record(),
+			OldHistory:  head.History(),
+			NewHistory:  next.History(),
+			PrevHistory: lineage.Prev(),
+		})
+		if _, err := lineage.Append(head.Version, head.Digest, next.Digest, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	edit := editCircuit{OldRecord: circuit.EmptyRecord(capacity), NewRecord: circuit.EmptyRecord(capacity)}
+	vk, proofs, publics := proveEdits(t, &edit, assignments)
+	layout, err := NewLayout(&edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from, to, err := VerifyLineage(vk, layout, proofs, publics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := lineage.Head()
+	if from.Version.(*big.Int).Sign() != 0 || to.Version.(*big.Int).Cmp(big.NewInt(2)) != 0 || to.Digest.(*big.Int).Cmp(head.Digest) != 0 || to.Issued.(*big.Int).Cmp(head.Issued) != 0 {
+		t.Fatalf("unexpected lineage from %+v to %+v", from, to)
+	}
+
+	// the edits out of order
+	var proofErr *batch.ProofError
+	if _, _, err := VerifyLineage(vk, layout, []groth16.Proof{proofs[1], proofs[0]}, []witness.Witness{publics[1], publics[0]}); !errors.As(err, &proofErr) || proofErr.Index != 1 {
+		t.Fatalf("expected the edits out of order to be rejected at edit 1, got %v", err)
+	}
+	// a proof of another edit
+	if _, _, err := VerifyLineage(vk, layout, []groth16.Proof{proofs[1], proofs[1]}, publics); !errors.As(err, &proofErr) || proofErr.Index != 0 {
+		t.Fatalf("expected the proof of another edit to be rejected at edit 0, got %v", err)
+	}
+}
+
+// TestVerifyPhdLineage proves two consecutive edits of the PhD profile with PhdEditCircuit and
+// verifies them as a lineage. The setup of the circuit takes minutes, so the test only runs with
+// DAC_PHD_LINEAGE set.
+func TestVerifyPhdLineage(t *testing.T) {
+	if os.Getenv("DAC_PHD_LINEAGE") == "" {
+		t.Skip("set DAC_PHD_LINEAGE to prove edits of the PhD profile")
+	}
+	const maxPub = 3
+	profiles := []string{"../cmd/phd_profile/oldProfile.json", "../cmd/phd_profile/newProfile.json", filepath.Join(t.TempDir(), "graduated.json")}
+	_, graduated, err := phd.ReadJSON(profiles[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	graduated.Status = "Graduated"
+	data, err := json.Marshal(graduated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(profiles[2], data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := policy.Load("../cmd/phd_profile/phdPolicy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit, err := phd.LimitFromPolicy(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers := make([]signature.Signer, 3)
+	for i := range signers {
+		if signers[i], err = circuit.NewIssuer(ecc.BN254); err != nil {
+			t.Fatal(err)
+		}
+	}
+	issuer, registrar, student := signers[0], signers[1], signers[2]
+	acl, err := phd.ACLFromPolicy(f, map[string]signature.PublicKey{"registrar": registrar.Public(), "student": student.Public()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := new(fr.Element).SetUint64(1234)
+	nonces := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+	sig, err := phd.SignProfile(issuer, profiles[0], key, nonces[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued, err := phd.EncryptProfile(profiles[0], key, nonces[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	lineage := history.New(phd.Cipher, issued)
+
+	// the registrar and the student approve the first edit, the registrar the graduation
+	var assignments []frontend.Circuit
+	for i, editors := range [][]signature.Signer{{registrar, student}, {registrar}} {
+		var approvals []phd.Approval
+		for j, editor := range editors {
+			approval, err := phd.ApproveEdit(editor, profiles[i], profiles[i+1], key, nonces[i], nonces[i+1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			approvals = append(approvals, phd.Approval{Editor: j, Signature: approval})
+		}
+		assignment, err := phd.GetAssignment(profiles[i], profiles[i+1], limit, key, big.NewInt(99), nonces[i], nonces[i+1], issuer.Public(), sig, acl, approvals, lineage, maxPub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assignments = append(assignments, &assignment)
+		record, err := phd.EncryptProfile(profiles[i+1], key, nonces[i+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		head, next := lineage.Head(), lineage.Next(record)
+		if _, err := lineage.Append(head.Version, head.Digest, next.Digest, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	edit := phd.InitPhdEditCircuit(maxPub)
+	vk, proofs, publics := proveEdits(t, &edit, assignments)
+	layout, err := NewLayout(&edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from, to, err := VerifyLineage(vk, layout, proofs, publics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := lineage.Head()
+	if from.Version.(*big.Int).Sign() != 0 || to.Version.(*big.Int).Cmp(big.NewInt(2)) != 0 || to.Digest.(*big.Int).Cmp(head.Digest) != 0 {
+		t.Fatalf("unexpected lineage from %+v to %+v", from, to)
+	}
+	if _, _, err := VerifyLineage(vk, layout, []groth16.Proof{proofs[1], proofs[0]}, []witness.Witness{publics[1], publics[0]}); err == nil {
+		t.Fatal("expected the edits out of order to be rejected")
+	}
+}
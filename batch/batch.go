@@ -0,0 +1,170 @@
+// Package batch verifies many Groth16 proofs of the same circuit at once. The verification
+// equations of the proofs are combined with random coefficients r_i, so that
+//
+//	Π e(r_i·A_i, B_i) · e(-Σ r_i·L_i, γ) · e(-Σ r_i·C_i, δ) · e(-(Σ r_i)·α, β) == 1
+//
+// with L_i = K_0 + Σ x_ij·K_j the public inputs of proof i, costs a single final
+// exponentiation and n+3 Miller loops instead of n verifications. A batch with an invalid
+// proof passes with probability 1/r at most.
+package batch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+)
+
+// verifyingKey holds the points of a Groth16 verifying key on BN254
+type verifyingKey struct {
+	alpha              bn254.G1Affine
+	beta, gamma        bn254.G2Affine
+	delta              bn254.G2Affine
+	k                  []bn254.G1Affine
+	negGamma, negDelta bn254.G2Affine
+}
+
+// proof holds the points of a Groth16 proof on BN254
+type proof struct {
+	ar, krs bn254.G1Affine
+	bs      bn254.G2Affine
+}
+
+// ProofError is the first invalid proof of a batch
+type ProofError struct {
+	Index int
+	Err   error
+}
+
+func (e *ProofError) Error() string {
+	return fmt.Sprintf("proof %d: %v", e.Index, e.Err)
+}
+
+func (e *ProofError) Unwrap() error {
+	return e.Err
+}
+
+// Verify checks proofs against vk, publics being their public witnesses in the same order.
+// The proofs and the key must be on BN254. When the batch fails, the first invalid proof is
+// looked for with groth16.Verify and returned as a *ProofError.
+func Verify(vk groth16.VerifyingKey, proofs []groth16.Proof, publics []witness.Witness) error {
+	if len(proofs) == 0 || len(proofs) != len(publics) {
+		return errors.New("every proof needs its public witness")
+	}
+	ok, err := check(vk, proofs, publics)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+	for i := range proofs {
+		if err := groth16.Verify(proofs[i], vk, publics[i]); err != nil {
+			return &ProofError{Index: i, Err: err}
+		}
+	}
+	return errors.New("pairing doesn't match")
+}
+
+func check(vk groth16.VerifyingKey, proofs []groth16.Proof, publics []witness.Witness) (bool, error) {
+	key, err := readVerifyingKey(vk)
+	if err != nil {
+		return false, err
+	}
+	n := len(proofs)
+	// the pairs e(r_i·A_i, B_i), then L with -γ, C with -δ and α with -β
+	P := make([]bn254.G1Affine, n, n+3)
+	Q := make([]bn254.G2Affine, n, n+3)
+	// scalars of the multi-exponentiations of K, Σ r_i·x_ij, and of the Krs, r_i
+	kScalars := make([]fr.Element, len(key.k))
+	r := make([]fr.Element, n)
+	krs := make([]bn254.G1Affine, n)
+	var rSum fr.Element
+	for i := range proofs {
+		p, err := readProof(proofs[i])
+		if err != nil {
+			return false, fmt.Errorf("proof %d: %w", i, err)
+		}
+		x, ok := publics[i].Vector().(fr.Vector)
+		if !ok || len(x) != len(key.k)-1 {
+			return false, fmt.Errorf("proof %d: the public witness does not match the verifying key", i)
+		}
+		if _, err := r[i].SetRandom(); err != nil {
+			return false, err
+		}
+		rSum.Add(&rSum, &r[i])
+		for j := range x {
+			var t fr.Element
+			t.Mul(&r[i], &x[j])
+			kScalars[j+1].Add(&kScalars[j+1], &t)
+		}
+		P[i].ScalarMultiplication(&p.ar, r[i].BigInt(new(big.Int)))
+		Q[i] = p.bs
+		krs[i] = p.krs
+	}
+	kScalars[0] = rSum
+
+	var l, c, a bn254.G1Affine
+	if _, err := l.MultiExp(key.k, kScalars, ecc.MultiExpConfig{}); err != nil {
+		return false, err
+	}
+	if _, err := c.MultiExp(krs, r, ecc.MultiExpConfig{}); err != nil {
+		return false, err
+	}
+	a.ScalarMultiplication(&key.alpha, rSum.BigInt(new(big.Int)))
+	a.Neg(&a)
+	P = append(P, l, c, a)
+	Q = append(Q, key.negGamma, key.negDelta, key.beta)
+	return bn254.PairingCheck(P, Q)
+}
+
+// readVerifyingKey reads the points of a verifying key from its serialization
+// [α]1,[β]1,[β]2,[γ]2,[δ]1,[δ]2,[Kvk]1
+func readVerifyingKey(vk groth16.VerifyingKey) (*verifyingKey, error) {
+	if vk.CurveID() != ecc.BN254 {
+		return nil, fmt.Errorf("batch verification is implemented on BN254, not %s", vk.CurveID())
+	}
+	var buf bytes.Buffer
+	if _, err := vk.WriteRawTo(&buf); err != nil {
+		return nil, err
+	}
+	var res verifyingKey
+	var betaG1, deltaG1 bn254.G1Affine
+	dec := bn254.NewDecoder(&buf)
+	for _, p := range []interface{}{&res.alpha, &betaG1, &res.beta, &res.gamma, &deltaG1, &res.delta, &res.k} {
+		if err := dec.Decode(p); err != nil {
+			return nil, err
+		}
+	}
+	if len(res.k) == 0 || vk.NbPublicWitness() != len(res.k)-1 {
+		return nil, errors.New("batch verification does not support circuits with commitments")
+	}
+	res.negGamma.Neg(&res.gamma)
+	res.negDelta.Neg(&res.delta)
+	return &res, nil
+}
+
+// readProof reads the points of a proof from its serialization, checking their subgroups
+func readProof(p groth16.Proof) (*proof, error) {
+	if p.CurveID() != ecc.BN254 {
+		return nil, fmt.Errorf("batch verification is implemented on BN254, not %s", p.CurveID())
+	}
+	var buf bytes.Buffer
+	if _, err := p.WriteRawTo(&buf); err != nil {
+		return nil, err
+	}
+	var res proof
+	dec := bn254.NewDecoder(&buf)
+	for _, p := range []interface{}{&res.ar, &res.bs, &res.krs} {
+		if err := dec.Decode(p); err != nil {
+			return nil, err
+		}
+	}
+	return &res, nil
+}
@@ -0,0 +1,101 @@
+package batch
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+// cubicCircuit proves the knowledge of X such that X³ + X + Offset == Y
+type cubicCircuit struct {
+	X      frontend.Variable
+	Offset frontend.Variable `gnark:",public"`
+	Y      frontend.Variable `gnark:",public"`
+}
+
+func (c *cubicCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(c.Y, api.Add(x3, c.X, c.Offset))
+	return nil
+}
+
+func proveCubic(tb testing.TB, n int) (groth16.VerifyingKey, []groth16.Proof, []witness.Witness) {
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	pk, vk, err := groth16.Setup(cs)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	proofs := make([]groth16.Proof, n)
+	publics := make([]witness.Witness, n)
+	for i := range proofs {
+		// the offset of the first proof is zero
+		assignment := cubicCircuit{X: i + 2, Offset: i, Y: (i+2)*(i+2)*(i+2) + i + 2 + i}
+		full, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if publics[i], err = full.Public(); err != nil {
+			tb.Fatal(err)
+		}
+		if proofs[i], err = groth16.Prove(cs, pk, full); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return vk, proofs, publics
+}
+
+func TestVerify(t *testing.T) {
+	vk, proofs, publics := proveCubic(t, 4)
+	if err := Verify(vk, proofs, publics); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(vk, proofs[:1], publics[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	// the proof of another statement
+	forged := append([]groth16.Proof(nil), proofs...)
+	forged[2] = proofs[1]
+	var proofErr *ProofError
+	if err := Verify(vk, forged, publics); !errors.As(err, &proofErr) || proofErr.Index != 2 {
+		t.Fatalf("expected proof 2 to be reported, got %v", err)
+	}
+	// two proofs swapped, each invalid on its own
+	swapped := []witness.Witness{publics[0], publics[2], publics[1], publics[3]}
+	if err := Verify(vk, proofs, swapped); err == nil {
+		t.Fatal("expected swapped public witnesses to be rejected")
+	}
+	if err := Verify(vk, proofs, publics[:3]); err == nil {
+		t.Fatal("expected a missing public witness to be rejected")
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	const n = 64
+	vk, proofs, publics := proveCubic(b, n)
+	b.Run(fmt.Sprintf("sequential/%d", n), func(b *testing.B) {
+		for k := 0; k < b.N; k++ {
+			for i := range proofs {
+				if err := groth16.Verify(proofs[i], vk, publics[i]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run(fmt.Sprintf("batch/%d", n), func(b *testing.B) {
+		for k := 0; k < b.N; k++ {
+			if err := Verify(vk, proofs, publics); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package circuit
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/signature"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/twistededwards"
+	"github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/std/signature/eddsa"
+)
+
+// ACL is the public list of the editors of a record and of the fields each of them may change.
+// The fields under control are given by their paths when the circuit is compiled; a field
+// outside them is only bound by its rules.
+type ACL struct {
+	Editors []eddsa.PublicKey
+	Allowed [][]frontend.Variable // Allowed[e][i] is 1 if Editors[e] may change the field of the i-th path
+}
+
+// EmptyACL returns an ACL of the given number of editors over the given number of fields, no
+// editor being allowed anything
+func EmptyACL(editors int, fields int) ACL {
+	res := ACL{Editors: make([]eddsa.PublicKey, editors), Allowed: make([][]frontend.Variable, editors)}
+	for e := range res.Editors {
+		res.Editors[e] = EmptyIssuerKey()
+		res.Allowed[e] = make([]frontend.Variable, fields)
+		for i := range res.Allowed[e] {
+			res.Allowed[e][i] = 0
+		}
+	}
+	return res
+}
+
+// CheckEditors asserts that every field at paths that differs between oldContent and
+// newContent may be changed by one of the editors approving the edit. editors are the public
+// indices in acl of the approving editors, the identity each of them is accountable with, and
+// approvals their private signatures of the edit from oldRecord to newRecord. All approvals are
+// checked: an edit approved by fewer editors than there are slots repeats one of them, and none
+// may claim an empty slot of acl.
+func CheckEditors(api frontend.API, paths []string, acl ACL, editors []frontend.Variable, approvals []eddsa.Signature, oldRecord Record, newRecord Record, oldContent interface{}, newContent interface{}) {
+	if len(editors) == 0 || len(editors) != len(approvals) {
+		panic("Invalid approvals")
+	}
+	if len(acl.Editors) != len(acl.Allowed) {
+		panic("Invalid ACL")
+	}
+	id, err := curveOf(api.Compiler().Field())
+	if err != nil {
+		panic(err)
+	}
+	curve, err := twistededwards.NewEdCurve(api, IssuerCurve(id))
+	if err != nil {
+		panic(err)
+	}
+	message := editDigest(api, oldRecord, newRecord)
+
+	// covered[i] counts the approving editors allowed to change the i-th field
+	covered := make([]frontend.Variable, len(paths))
+	for i := range covered {
+		covered[i] = 0
+	}
+	for k := range editors {
+		key := eddsa.PublicKey{A: twistededwards.Point{X: 0, Y: 0}}
+		found := frontend.Variable(0)
+		allowed := make([]frontend.Variable, len(paths))
+		for i := range allowed {
+			allowed[i] = 0
+		}
+		for e := range acl.Editors {
+			if len(acl.Allowed[e]) != len(paths) {
+				panic("Invalid ACL")
+			}
+			selected := isEqual(api, editors[k], e)
+			found = api.Add(found, selected)
+			key.A.X = api.Add(key.A.X, api.Mul(selected, acl.Editors[e].A.X))
+			key.A.Y = api.Add(key.A.Y, api.Mul(selected, acl.Editors[e].A.Y))
+			for i := range allowed {
+				api.AssertIsBoolean(acl.Allowed[e][i])
+				allowed[i] = api.Add(allowed[i], api.Mul(selected, acl.Allowed[e][i]))
+			}
+		}
+		api.AssertIsEqual(found, 1)
+		// the slot holds an editor: the key (0, 0) of an empty slot, like the identity (0, 1),
+		// has X = 0 and would let anyone approve in its name
+		api.AssertIsDifferent(key.A.X, 0)
+		for i := range covered {
+			covered[i] = api.Add(covered[i], allowed[i])
+		}
+
+		hash, err := mimc.NewMiMC(api)
+		if err != nil {
+			panic(err)
+		}
+		if err := eddsa.Verify(curve, approvals[k], message, key, &hash); err != nil {
+			panic(err)
+		}
+	}
+
+	for i, path := range paths {
+		changed := api.Sub(1, isEqualInterface(api, fieldByPath(oldContent, path), fieldByPath(newContent, path)))
+		api.AssertIsEqual(api.Mul(changed, api.IsZero(covered[i])), 0)
+	}
+}
+
+// editDigest is the message signed by the editors of the edit from oldRecord to newRecord
+func editDigest(api frontend.API, oldRecord Record, newRecord Record) frontend.Variable {
+	return hashItems(api, []frontend.Variable{recordDigest(api, oldRecord), recordDigest(api, newRecord)})
+}
+
+// SignEdit is the approval by editor of the edit from oldRecord to newRecord, checked by
+// CheckEditors
+func (c Cipher) SignEdit(editor signature.Signer, oldRecord *Ciphertext, newRecord *Ciphertext) ([]byte, error) {
+	return editor.Sign(c.editDigestBytes(oldRecord, newRecord), mimcHashes[c.Curve].New())
+}
+
+// VerifyEdit is the native counterpart of the approval check of CheckEditors
+func (c Cipher) VerifyEdit(editor signature.PublicKey, sig []byte, oldRecord *Ciphertext, newRecord *Ciphertext) (bool, error) {
+	return editor.Verify(sig, c.editDigestBytes(oldRecord, newRecord), mimcHashes[c.Curve].New())
+}
+
+func (c Cipher) editDigestBytes(oldRecord *Ciphertext, newRecord *Ciphertext) []byte {
+	size := (c.Curve.ScalarField().BitLen() + 7) / 8
+	digest := c.Primitive.HashOn(c.Curve, c.RecordDigest(oldRecord.Nonce, oldRecord.Blocks), c.RecordDigest(newRecord.Nonce, newRecord.Blocks))
+	return digest.FillBytes(make([]byte, size))
+}
+
+// NewACL builds the assignment of an ACL over paths for circuits compiled on curve: editors[e]
+// may change the fields in fields[e]. The ACL holds capacity editors, the ones beyond
+// len(editors) are allowed nothing.
+func NewACL(curve ecc.ID, paths []string, editors []signature.PublicKey, fields [][]string, capacity int) (ACL, error) {
+	if len(editors) != len(fields) {
+		return ACL{}, errors.New("every editor needs its fields")
+	}
+	if len(editors) > capacity {
+		return ACL{}, fmt.Errorf("%d editors, at most %d are supported", len(editors), capacity)
+	}
+	index := map[string]int{}
+	for i, path := range paths {
+		index[path] = i
+	}
+	res := EmptyACL(capacity, len(paths))
+	for e := range editors {
+		res.Editors[e].Assign(IssuerCurve(curve), editors[e].Bytes())
+		for _, path := range fields[e] {
+			i, ok := index[path]
+			if !ok {
+				return ACL{}, fmt.Errorf("%s is not under access control", path)
+			}
+			res.Allowed[e][i] = 1
+		}
+	}
+	return res, nil
+}
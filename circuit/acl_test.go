@@ -0,0 +1,124 @@
+package circuit
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	tedwards "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/consensys/gnark-crypto/signature"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/twistededwards"
+	"github.com/consensys/gnark/std/signature/eddsa"
+	"github.com/consensys/gnark/test"
+)
+
+var covidACLPaths = []string{"LatestVaccine", "CovidTest", "MedicalInsuranceStatus"}
+
+type CovidACLCircuit struct {
+	OldRecord  Record              `gnark:",public"`
+	NewRecord  Record              `gnark:",public"`
+	ACL        ACL                 `gnark:",public"`
+	Editors    []frontend.Variable `gnark:",public"`
+	Approvals  []eddsa.Signature
+	OldContent CovidRecord
+	NewContent CovidRecord
+}
+
+func (c *CovidACLCircuit) Define(api frontend.API) error {
+	CheckEditors(api, covidACLPaths, c.ACL, c.Editors, c.Approvals, c.OldRecord, c.NewRecord, c.OldContent, c.NewContent)
+	return nil
+}
+
+func TestCheckEditors(t *testing.T) {
+	oldJSON := `{"LatestVaccine":{"VaccineType":"Pfizer","Dosage":2}}`
+	newJSON := `{"LatestVaccine":{"VaccineType":"Moderna","Dosage":3}}`
+	key := new(fr.Element).SetUint64(42)
+	cipher := Cipher{ecc.BN254, MimcPrimitive}
+	oldRecord := makeTestRecord(cipher, oldJSON, key, 7, testMaxRecLen)
+	newRecord := makeTestRecord(cipher, newJSON, key, 8, testMaxRecLen)
+
+	doctor, err := NewIssuer(ecc.BN254)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insurer, err := NewIssuer(ecc.BN254)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl, err := NewACL(ecc.BN254, covidACLPaths, []signature.PublicKey{doctor.Public(), insurer.Public()},
+		[][]string{{"LatestVaccine", "CovidTest"}, {"MedicalInsuranceStatus"}}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approve := func(editor signature.Signer) eddsa.Signature {
+		sig, err := cipher.SignEdit(editor, oldRecord, newRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := cipher.VerifyEdit(editor.Public(), sig, oldRecord, newRecord); !ok || err != nil {
+			t.Fatal("the approval does not verify")
+		}
+		var res eddsa.Signature
+		res.Assign(IssuerCurve(ecc.BN254), sig)
+		return res
+	}
+	assignment := func(editors []frontend.Variable, approvals []eddsa.Signature) *CovidACLCircuit {
+		return &CovidACLCircuit{
+			OldRecord:  oldRecord.Record(),
+			NewRecord:  newRecord.Record(),
+			ACL:        acl,
+			Editors:    editors,
+			Approvals:  approvals,
+			OldContent: makeTestCovidRecord("Pfizer", 2, nil, "Active"),
+			NewContent: makeTestCovidRecord("Moderna", 3, nil, "Active"),
+		}
+	}
+	circuit := &CovidACLCircuit{
+		OldRecord:  EmptyRecord(testMaxRecLen),
+		NewRecord:  EmptyRecord(testMaxRecLen),
+		ACL:        EmptyACL(3, len(covidACLPaths)),
+		Editors:    make([]frontend.Variable, 2),
+		Approvals:  []eddsa.Signature{EmptySignature(), EmptySignature()},
+		OldContent: makeTestCovidRecord("", 0, nil, ""),
+		NewContent: makeTestCovidRecord("", 0, nil, ""),
+	}
+
+	byDoctor := approve(doctor)
+	byInsurer := approve(insurer)
+	if err := test.IsSolved(circuit, assignment([]frontend.Variable{0, 1}, []eddsa.Signature{byDoctor, byInsurer}), ecc.BN254.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+	// the doctor alone, repeated in the second slot
+	if err := test.IsSolved(circuit, assignment([]frontend.Variable{0, 0}, []eddsa.Signature{byDoctor, byDoctor}), ecc.BN254.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+	// the insurer may not change the vaccine
+	if err := test.IsSolved(circuit, assignment([]frontend.Variable{1, 1}, []eddsa.Signature{byInsurer, byInsurer}), ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected an edit outside the fields of the editor to be rejected")
+	}
+	// the insurer claiming to be the doctor
+	if err := test.IsSolved(circuit, assignment([]frontend.Variable{0, 0}, []eddsa.Signature{byInsurer, byInsurer}), ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected the approval of another editor to be rejected")
+	}
+	// the third editor is allowed nothing
+	if err := test.IsSolved(circuit, assignment([]frontend.Variable{2, 2}, []eddsa.Signature{byDoctor, byDoctor}), ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected an unused editor to be rejected")
+	}
+	// an empty slot, even allowed every field, holds no editor to approve in the name of
+	for i := range acl.Allowed[2] {
+		acl.Allowed[2][i] = 1
+	}
+	for _, approval := range []eddsa.Signature{EmptySignature(), byDoctor} {
+		if err := test.IsSolved(circuit, assignment([]frontend.Variable{2, 2}, []eddsa.Signature{approval, approval}), ecc.BN254.ScalarField()); err == nil {
+			t.Fatal("expected an approval in an empty slot to be rejected")
+		}
+	}
+	// nor one keyed with the identity, for which R = [S]G forges any approval
+	base := tedwards.GetEdwardsCurve().Base
+	acl.Editors[2] = eddsa.PublicKey{A: twistededwards.Point{X: 0, Y: 1}}
+	forged := eddsa.Signature{R: twistededwards.Point{X: base.X, Y: base.Y}, S: 1}
+	if err := test.IsSolved(circuit, assignment([]frontend.Variable{2, 2}, []eddsa.Signature{forged, forged}), ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected an approval in the name of the identity to be rejected")
+	}
+}
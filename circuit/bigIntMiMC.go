@@ -1,11 +1,18 @@
 package circuit
 
 import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark-crypto/hash"
+	"github.com/consensys/gnark/frontend"
 	"golang.org/x/crypto/sha3"
-	"math/big"
-	"sync"
 )
 
 const (
@@ -115,3 +122,191 @@ func EncryptMimcFr(key fr.Element, message fr.Element) fr.Element {
 	d.h = key
 	return d.encrypt(message)
 }
+
+// EncryptMimc is the native counterpart of encryptMimc on the scalar field of curve
+func EncryptMimc(curve ecc.ID, key *big.Int, message *big.Int) *big.Int {
+	params, ok := mimcParams[curve]
+	if !ok {
+		panic("unknown curve id")
+	}
+	modulus := curve.ScalarField()
+	exponent := big.NewInt(params.exponent)
+	m := new(big.Int).Mod(message, modulus)
+	for _, c := range params.constants() {
+		m.Add(m, key).Add(m, &c)
+		m.Exp(m, exponent, modulus)
+	}
+	return m.Add(m, key).Mod(m, modulus)
+}
+
+var mimcHashes = map[ecc.ID]hash.Hash{
+	ecc.BN254:     hash.MIMC_BN254,
+	ecc.BLS12_381: hash.MIMC_BLS12_381,
+	ecc.BLS12_377: hash.MIMC_BLS12_377,
+	ecc.BW6_761:   hash.MIMC_BW6_761,
+}
+
+// Cipher computes natively what the circuits check when compiled on the scalar field of Curve
+// with Primitive. The functions below without a Cipher use MiMC.
+type Cipher struct {
+	Curve     ecc.ID
+	Primitive Primitive
+}
+
+// CommitKey is the native counterpart of commit: the hiding commitment to key under blinding
+func CommitKey(curve ecc.ID, key *big.Int, blinding *big.Int) *big.Int {
+	return Cipher{curve, MimcPrimitive}.CommitKey(key, blinding)
+}
+
+func (c Cipher) CommitKey(key *big.Int, blinding *big.Int) *big.Int {
+	return c.Primitive.HashOn(c.Curve, key, blinding)
+}
+
+// NewBlinding draws the blinding factor of a key commitment on the scalar field of curve
+func NewBlinding(curve ecc.ID) (*big.Int, error) {
+	return rand.Int(rand.Reader, curve.ScalarField())
+}
+
+// EncryptRecord is the native counterpart of encrypt: the encoded record is cut into blocks of
+// MergeLen bytes, each read as a little endian integer and added to MiMC(key, nonce||counter)
+func EncryptRecord(curve ecc.ID, record []byte, key *big.Int, nonce *big.Int) []*big.Int {
+	return Cipher{curve, MimcPrimitive}.EncryptRecord(record, key, nonce)
+}
+
+func (c Cipher) EncryptRecord(record []byte, key *big.Int, nonce *big.Int) []*big.Int {
+	if nonce.Sign() < 0 || nonce.BitLen() > NonceBits {
+		panic("Invalid Nonce")
+	}
+	var res []*big.Int
+	for i := 0; i < len(record); i += MergeLen {
+		end := i + MergeLen
+		if end > len(record) {
+			end = len(record)
+		}
+		blk := make([]byte, end-i)
+		for j := range blk {
+			blk[len(blk)-1-j] = record[i+j]
+		}
+		counter := new(big.Int).Lsh(nonce, counterBits)
+		counter.Add(counter, big.NewInt(int64(i/MergeLen)))
+		blkEnc := c.Primitive.EncryptOn(c.Curve, key, counter)
+		blkEnc.Add(blkEnc, new(big.Int).SetBytes(blk)).Mod(blkEnc, c.Curve.ScalarField())
+		res = append(res, blkEnc)
+	}
+	return res
+}
+
+// DecryptRecord inverts EncryptRecord: in counter mode each block is recovered by subtracting
+// its key stream, so MiMC itself is never inverted. Trailing zero blocks, the padding of a
+// Ciphertext, are dropped. It fails if the blocks do not decode to JSON, e.g. under a wrong key
+// or nonce.
+func DecryptRecord(curve ecc.ID, blocks []*big.Int, key *big.Int, nonce *big.Int) ([]byte, error) {
+	return Cipher{curve, MimcPrimitive}.DecryptRecord(blocks, key, nonce)
+}
+
+func (c Cipher) DecryptRecord(blocks []*big.Int, key *big.Int, nonce *big.Int) ([]byte, error) {
+	if nonce.Sign() < 0 || nonce.BitLen() > NonceBits {
+		return nil, errors.New("invalid nonce")
+	}
+	for len(blocks) > 0 && blocks[len(blocks)-1].Sign() == 0 {
+		blocks = blocks[:len(blocks)-1]
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), 8*MergeLen)
+	var res []byte
+	for i, blkEnc := range blocks {
+		counter := new(big.Int).Lsh(nonce, counterBits)
+		counter.Add(counter, big.NewInt(int64(i)))
+		blk := new(big.Int).Sub(blkEnc, c.Primitive.EncryptOn(c.Curve, key, counter))
+		blk.Mod(blk, c.Curve.ScalarField())
+		if blk.Cmp(limit) >= 0 {
+			return nil, errors.New("the record does not decrypt under this key")
+		}
+		buf := blk.FillBytes(make([]byte, MergeLen))
+		// little endian, the last block is padded with zero bytes
+		n := 0
+		for n < MergeLen && buf[MergeLen-1-n] != 0 {
+			res = append(res, buf[MergeLen-1-n])
+			n++
+		}
+		if i < len(blocks)-1 && n < MergeLen || blk.Cmp(new(big.Int).Lsh(big.NewInt(1), uint(8*n))) >= 0 {
+			return nil, errors.New("the record does not decrypt under this key")
+		}
+	}
+	if !json.Valid(res) {
+		return nil, errors.New("the record does not decrypt to JSON")
+	}
+	return res, nil
+}
+
+// NewNonce draws the random nonce of a record
+func NewNonce() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), NonceBits))
+}
+
+// Ciphertext is the native counterpart of Record
+type Ciphertext struct {
+	Nonce  *big.Int
+	Blocks []*big.Int
+	Tag    *big.Int
+}
+
+// Encrypt encrypts an encoded record under key and nonce, pads its blocks with zeros to the
+// capacity of the circuit and tags it
+func Encrypt(curve ecc.ID, record []byte, key *big.Int, nonce *big.Int, capacity int) (*Ciphertext, error) {
+	return Cipher{curve, MimcPrimitive}.Encrypt(record, key, nonce, capacity)
+}
+
+func (c Cipher) Encrypt(record []byte, key *big.Int, nonce *big.Int, capacity int) (*Ciphertext, error) {
+	blocks := c.EncryptRecord(record, key, nonce)
+	if len(blocks) > capacity {
+		return nil, errors.New("the record does not fit in the capacity")
+	}
+	for len(blocks) < capacity {
+		blocks = append(blocks, big.NewInt(0))
+	}
+	res := &Ciphertext{Nonce: nonce, Blocks: blocks}
+	res.Tag = c.RecordTag(key, nonce, blocks)
+	return res, nil
+}
+
+// RecordTag is the native counterpart of recordTag
+func RecordTag(curve ecc.ID, key *big.Int, nonce *big.Int, blocks []*big.Int) *big.Int {
+	return Cipher{curve, MimcPrimitive}.RecordTag(key, nonce, blocks)
+}
+
+func (c Cipher) RecordTag(key *big.Int, nonce *big.Int, blocks []*big.Int) *big.Int {
+	macKey := c.Primitive.EncryptOn(c.Curve, key, macDomain)
+	return c.Primitive.EncryptOn(c.Curve, macKey, c.RecordDigest(nonce, blocks))
+}
+
+// Verify tells whether the tag of ct matches its nonce and blocks, without running a proof
+func (c Cipher) Verify(ct *Ciphertext, key *big.Int) bool {
+	return c.RecordTag(key, ct.Nonce, ct.Blocks).Cmp(ct.Tag) == 0
+}
+
+// Decrypt checks the tag of ct and returns the encoded record
+func (c Cipher) Decrypt(ct *Ciphertext, key *big.Int) ([]byte, error) {
+	if !c.Verify(ct, key) {
+		return nil, errors.New("the tag of the record does not verify")
+	}
+	return c.DecryptRecord(ct.Blocks, key, ct.Nonce)
+}
+
+// Verify tells whether the tag of c matches its nonce and blocks under MiMC
+func (c *Ciphertext) Verify(curve ecc.ID, key *big.Int) bool {
+	return Cipher{curve, MimcPrimitive}.Verify(c, key)
+}
+
+// Decrypt checks the tag of c under MiMC and returns the encoded record
+func (c *Ciphertext) Decrypt(curve ecc.ID, key *big.Int) ([]byte, error) {
+	return Cipher{curve, MimcPrimitive}.Decrypt(c, key)
+}
+
+// Record returns c as the assignment of a Record
+func (c *Ciphertext) Record() Record {
+	res := Record{Blocks: make([]frontend.Variable, len(c.Blocks)), Nonce: c.Nonce, Tag: c.Tag}
+	for i := range c.Blocks {
+		res.Blocks[i] = c.Blocks[i]
+	}
+	return res
+}
@@ -0,0 +1,199 @@
+package circuit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// WithCanonicalJSON returns api with which records are encoded in the JSON Canonicalization
+// Scheme of RFC 8785, as CanonicalJSON writes them: the members of a dict sorted by key, numbers
+// as ECMAScript writes them, e.g. -12.5 for a decimal of X -1250 and Scale 2, and strings only
+// escaped where JSON requires it. Integers must then have at most 15 digits and a Scale of at
+// most 6, so that they are exact doubles written without exponent.
+//
+//	func (c *EditCircuit) Define(api frontend.API) error {
+//		api = circuit.WithCanonicalJSON(api)
+//		...
+func WithCanonicalJSON(api frontend.API) frontend.API {
+	o := optionsOf(api)
+	o.canonical = true
+	return o
+}
+
+func isCanonical(api frontend.API) bool {
+	return optionsOf(api).canonical
+}
+
+// CanonicalJSON returns the canonical form of the JSON value data in the JSON Canonicalization
+// Scheme of RFC 8785, so that semantically equal values have the same bytes. data must be
+// I-JSON: valid UTF-8 without duplicated keys.
+func CanonicalJSON(data []byte) ([]byte, error) {
+	if !utf8.Valid(data) {
+		return nil, errors.New("invalid UTF-8")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, dec); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid JSON: data after the value")
+	}
+	return buf.Bytes(), nil
+}
+
+// writeCanonical writes the next value of dec in canonical form
+func writeCanonical(buf *bytes.Buffer, dec *json.Decoder) error {
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '[' {
+			buf.WriteByte('[')
+			for i := 0; dec.More(); i++ {
+				if i != 0 {
+					buf.WriteByte(',')
+				}
+				if err := writeCanonical(buf, dec); err != nil {
+					return err
+				}
+			}
+			buf.WriteByte(']')
+		} else {
+			members := map[string][]byte{}
+			var keys []string
+			for dec.More() {
+				token, err := dec.Token()
+				if err != nil {
+					return fmt.Errorf("invalid JSON: %w", err)
+				}
+				key := token.(string)
+				if _, ok := members[key]; ok {
+					return fmt.Errorf("duplicated key %q", key)
+				}
+				var value bytes.Buffer
+				if err := writeCanonical(&value, dec); err != nil {
+					return err
+				}
+				members[key] = value.Bytes()
+				keys = append(keys, key)
+			}
+			sort.Slice(keys, func(i, j int) bool { return lessUTF16(keys[i], keys[j]) })
+			buf.WriteByte('{')
+			for i, key := range keys {
+				if i != 0 {
+					buf.WriteByte(',')
+				}
+				buf.WriteByte('"')
+				buf.Write(escapeCanonical(key))
+				buf.WriteString(`":`)
+				buf.Write(members[key])
+			}
+			buf.WriteByte('}')
+		}
+		// the closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+	case string:
+		buf.WriteByte('"')
+		buf.Write(escapeCanonical(t))
+		buf.WriteByte('"')
+	case json.Number:
+		n, err := canonicalNumber(t)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(n)
+	case bool:
+		buf.WriteString(strconv.FormatBool(t))
+	case nil:
+		buf.WriteString("null")
+	}
+	return nil
+}
+
+// canonicalNumber writes n as ECMAScript writes the double closest to it: the shortest digits
+// reading back as the same double, without exponent from 1e-6 included to 1e21 excluded
+func canonicalNumber(n json.Number) (string, error) {
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil || math.IsInf(f, 0) {
+		return "", fmt.Errorf("number %s is not a double", n)
+	}
+	if f == 0 {
+		return "0", nil
+	}
+	format := byte('e')
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		format = 'f'
+	}
+	res := strconv.FormatFloat(f, format, -1, 64)
+	// ECMAScript writes e+7 and e-7 where Go writes e+07 and e-07
+	if i := strings.IndexByte(res, 'e'); i >= 0 && res[i+2] == '0' {
+		res = res[:i+2] + res[i+3:]
+	}
+	return res, nil
+}
+
+// escapeCanonical returns the characters of the JSON string s without its quotes, escaped as
+// RFC 8785 escapes them: '"' and '\\', and the control characters, as \b, \f, \n, \r, \t or
+// \u00XX with lowercase hexadecimal digits
+func escapeCanonical(s string) []byte {
+	var res []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			res = append(res, '\\', c)
+		case c < 0x20:
+			escaped := false
+			for _, e := range shortEscapes {
+				if int(c) == e.char {
+					res = append(res, '\\', byte(e.letter))
+					escaped = true
+				}
+			}
+			if !escaped {
+				res = append(res, fmt.Sprintf(`\u%04x`, c)...)
+			}
+		default:
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// lessUTF16 orders keys by their UTF-16 code units as RFC 8785 sorts the members of an object
+func lessUTF16(a, b string) bool {
+	x, y := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] != y[i] {
+			return x[i] < y[i]
+		}
+	}
+	return len(x) < len(y)
+}
+
+// canonicalOrder returns the indices of the members of dict sorted by key
+func canonicalOrder(dict Dict) []int {
+	order := make([]int, len(dict.names))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return lessUTF16(dict.names[order[i]], dict.names[order[j]]) })
+	return order
+}
@@ -0,0 +1,118 @@
+package circuit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	for _, tc := range []struct {
+		in, out string
+	}{
+		// the example of RFC 8785 section 3.2.2
+		{`{"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		   "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+		   "literals": [null, true, false]}`,
+			`{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`},
+		// the keys of section 3.2.3, sorted by UTF-16 code units
+		{`{"\u20ac": 1, "\r": 2, "\ufb33": 3, "1": 4, "\ud83d\ude00": 5, "\u0080": 6, "\u00f6": 7}`,
+			"{\"\\r\":2,\"1\":4,\"\u0080\":6,\"ö\":7,\"€\":1,\"😀\":5,\"\ufb33\":3}"},
+		{`[-0, 1e21, 1e-7, 0.000001, 100, 123456789012345678901, -12.50, "<&>\u2028"]`,
+			"[0,1e+21,1e-7,0.000001,100,123456789012345680000,-12.5,\"<&>\u2028\"]"},
+	} {
+		res, err := CanonicalJSON([]byte(tc.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(res) != tc.out {
+			t.Errorf("expected %s, got %s", tc.out, res)
+		}
+	}
+
+	// the same profile in another layout
+	a, _ := CanonicalJSON([]byte(`{"status": "Ongoing", "duration": {"start": 1.5e9, "end": 1700000000}}`))
+	b, _ := CanonicalJSON([]byte(`{"duration":{"end":17e8,"start":1500000000.0},"status":"Ongoing"}`))
+	if string(a) != string(b) {
+		t.Fatalf("expected %s and %s to be equal", a, b)
+	}
+
+	for _, in := range []string{`{"a": 1, "a": 2}`, "\"\xff\"", `{"a": 1} 2`, `[1e400]`, `{"a": }`} {
+		if _, err := CanonicalJSON([]byte(in)); err == nil {
+			t.Errorf("expected %s to be rejected", in)
+		}
+	}
+}
+
+type entry struct {
+	Title   String         `json:"title"`
+	Balance Integer        `json:"balance"`
+	Advisor OptionalString `json:"advisor,omitempty"`
+	GPA     Integer        `json:"GPA"`
+	Year    Integer        `json:"year"`
+}
+
+type entryJSON struct {
+	Title   string      `json:"title"`
+	Balance json.Number `json:"balance"`
+	Advisor *string     `json:"advisor,omitempty"`
+	GPA     json.Number `json:"GPA"`
+	Year    int64       `json:"year"`
+}
+
+func makeEntry(in entryJSON) entry {
+	return entry{
+		Title:   NewString(in.Title, 24),
+		Balance: NewDecimal(string(in.Balance), 8, 2),
+		Advisor: NewOptionalString(in.Advisor, 8),
+		GPA:     NewDecimal(string(in.GPA), 3, 2),
+		Year:    NewInteger(in.Year, 4),
+	}
+}
+
+// canonicalCircuit asserts that Content is encoded as the canonical JSON Expected
+type canonicalCircuit struct {
+	Content  entry
+	Expected String `gnark:",public"`
+}
+
+func (c *canonicalCircuit) Define(api frontend.API) error {
+	api = WithCanonicalJSON(api)
+	assertEncoding(api, c.Content, c.Expected)
+	return nil
+}
+
+func TestEncodeCanonical(t *testing.T) {
+	advisor := "Alice"
+	for _, in := range []entryJSON{
+		{Title: "<R&D> für\u2028alle\n", Balance: "-12.50", Advisor: &advisor, GPA: "3.00", Year: 2024},
+		{Title: `"ZK"`, Balance: "0.05", GPA: "0.00", Year: 0},
+		{Title: "", Balance: "-123456.70", GPA: "1.01", Year: 1},
+	} {
+		data, err := json.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		canonical, err := CanonicalJSON(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := NewString(string(canonical), len(canonical)+1)
+		circuit := &canonicalCircuit{Content: makeEntry(entryJSON{Balance: "0.00", GPA: "0.00"}), Expected: make(String, len(expected))}
+		if err := test.IsSolved(circuit, &canonicalCircuit{Content: makeEntry(in), Expected: expected}, ecc.BN254.ScalarField()); err != nil {
+			t.Fatalf("%s: %v", canonical, err)
+		}
+	}
+
+	// the layout of encoding/json
+	data, _ := json.Marshal(entryJSON{Title: "x", Balance: "1.50", GPA: "3.85", Year: 2024})
+	expected := NewString(string(data), len(data)+1)
+	circuit := &canonicalCircuit{Content: makeEntry(entryJSON{Balance: "0.00", GPA: "0.00"}), Expected: make(String, len(expected))}
+	assignment := &canonicalCircuit{Content: makeEntry(entryJSON{Title: "x", Balance: "1.50", GPA: "3.85", Year: 2024}), Expected: expected}
+	if err := test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected the struct order to be rejected")
+	}
+}
@@ -1,12 +1,9 @@
 package circuit
 
-import (
-	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/std/hash/mimc"
-)
+import "github.com/consensys/gnark/frontend"
 
-func commit(api frontend.API, msg frontend.Variable) frontend.Variable {
-	hash, _ := mimc.NewMiMC(api)
-	hash.Write(msg)
-	return hash.Sum()
+// commit is the hiding commitment H(msg, blinding): with a fresh random blinding for each
+// credential, two commitments to the same key cannot be linked
+func commit(api frontend.API, msg frontend.Variable, blinding frontend.Variable) frontend.Variable {
+	return hashItems(api, []frontend.Variable{msg, blinding})
 }
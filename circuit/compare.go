@@ -1,6 +1,10 @@
 package circuit
 
 import (
+	"math/big"
+	"reflect"
+
+	"github.com/Nullus-Labs/IDEA-DAC/dfa"
 	"github.com/consensys/gnark/frontend"
 )
 
@@ -12,11 +16,11 @@ func checkAppendOnlyPhd(api frontend.API, oldContent []Publication, newContent [
 	notEqual := frontend.Variable(0)
 	postEqual := frontend.Variable(1)
 	for i := 0; i < len(oldContent); i++ {
-		checkNone := api.And(oldContent[i].IsEmpty(api), newContent[i].IsEmpty(api))
-		postEqual = api.Select(notEqual, api.And(checkNone, postEqual), postEqual)
-		preEqual = api.And(preEqual, isEqualInterface(api, oldContent[i].Title, newContent[i].Title))
-		preEqual = api.And(preEqual, isEqualInterface(api, oldContent[i].Year, newContent[i].Year))
-		notEqual = api.Select(preEqual, api.And(frontend.Variable(0), notEqual), frontend.Variable(1))
+		checkNone := and(api, oldContent[i].IsEmpty(api), newContent[i].IsEmpty(api))
+		postEqual = api.Select(notEqual, and(api, checkNone, postEqual), postEqual)
+		preEqual = and(api, preEqual, isEqualInterface(api, oldContent[i].Title, newContent[i].Title))
+		preEqual = and(api, preEqual, isEqualInterface(api, oldContent[i].Year, newContent[i].Year))
+		notEqual = api.Select(preEqual, and(api, frontend.Variable(0), notEqual), frontend.Variable(1))
 	}
 	return postEqual
 }
@@ -29,11 +33,27 @@ func checkAppendOnlyCovid(api frontend.API, oldContent []CovidTest, newContent [
 	notEqual := frontend.Variable(0)
 	postEqual := frontend.Variable(1)
 	for i := 0; i < len(oldContent); i++ {
-		checkNone := api.And(oldContent[i].IsEmpty(api), newContent[i].IsEmpty(api))
-		postEqual = api.Select(notEqual, api.And(checkNone, postEqual), postEqual)
-		preEqual = api.And(preEqual, isEqualInterface(api, oldContent[i].TestDate, newContent[i].TestDate))
-		preEqual = api.And(preEqual, isEqualInterface(api, oldContent[i].Result, newContent[i].Result))
-		notEqual = api.Select(preEqual, api.And(frontend.Variable(0), notEqual), frontend.Variable(1))
+		checkNone := and(api, oldContent[i].IsEmpty(api), newContent[i].IsEmpty(api))
+		postEqual = api.Select(notEqual, and(api, checkNone, postEqual), postEqual)
+		preEqual = and(api, preEqual, isEqualInterface(api, oldContent[i].TestDate, newContent[i].TestDate))
+		preEqual = and(api, preEqual, isEqualInterface(api, oldContent[i].Result, newContent[i].Result))
+		notEqual = api.Select(preEqual, and(api, frontend.Variable(0), notEqual), frontend.Variable(1))
+	}
+	return postEqual
+}
+
+func checkAppendOnly(api frontend.API, oldContent Array, newContent Array) frontend.Variable {
+	if len(oldContent) != len(newContent) {
+		panic("oldContent and newContent should have the same length")
+	}
+	preEqual := frontend.Variable(1)
+	notEqual := frontend.Variable(0)
+	postEqual := frontend.Variable(1)
+	for i := 0; i < len(oldContent); i++ {
+		checkNone := and(api, oldContent[i].IsEmpty(api), newContent[i].IsEmpty(api))
+		postEqual = api.Select(notEqual, and(api, checkNone, postEqual), postEqual)
+		preEqual = and(api, preEqual, isEqualInterface(api, oldContent[i], newContent[i]))
+		notEqual = api.Select(preEqual, and(api, frontend.Variable(0), notEqual), frontend.Variable(1))
 	}
 	return postEqual
 }
@@ -47,6 +67,18 @@ func isEqualInterface(api frontend.API, a interface{}, b interface{}) frontend.V
 		if y, ok2 := b.(String); ok2 {
 			return isEqualString(api, x, y)
 		}
+	} else if x, ok := a.(Bool); ok {
+		if y, ok2 := b.(Bool); ok2 {
+			return isEqual(api, x.X, y.X)
+		}
+	} else if _, ok := a.(Null); ok {
+		if _, ok2 := b.(Null); ok2 {
+			return frontend.Variable(1)
+		}
+	} else if x, ok := a.(optional); ok {
+		if y, ok2 := b.(optional); ok2 && reflect.TypeOf(a) == reflect.TypeOf(b) {
+			return isEqualOptional(api, x, y)
+		}
 	} else if x, ok := a.(Array); ok {
 		if y, ok2 := b.(Array); ok2 {
 			return isEqualArray(api, x, y)
@@ -55,6 +87,15 @@ func isEqualInterface(api frontend.API, a interface{}, b interface{}) frontend.V
 		if y, ok2 := b.(Dict); ok2 {
 			return isEqualDict(api, x, y)
 		}
+	} else if reflect.TypeOf(a) == reflect.TypeOf(b) {
+		switch reflect.TypeOf(a).Kind() {
+		case reflect.Struct:
+			return isEqualDict(api, toDict(api, a, MaxKeyLen), toDict(api, b, MaxKeyLen))
+		case reflect.Slice:
+			return isEqualArray(api, toArray(api, a), toArray(api, b))
+		default:
+			panic("Invalid Type")
+		}
 	} else {
 		panic("Invalid Type")
 	}
@@ -95,26 +136,38 @@ func isEqualString(api frontend.API, x String, y String) frontend.Variable {
 	return isEqual(api, judge, len(x))
 }
 
+// isEqualOptional ignores the values of null optionals
+func isEqualOptional(api frontend.API, a optional, b optional) frontend.Variable {
+	sameValue := api.Or(a.null(), isEqualInterface(api, a.value(), b.value()))
+	return and(api, isEqual(api, a.null(), b.null()), sameValue)
+}
+
 func isEqualInteger(api frontend.API, a Integer, b Integer) frontend.Variable {
 	return isEqual(api, a.X, b.X)
 }
 
+// checkWithinRange compares signed values, negative ones being above half the field: all are
+// shifted by half the field first
 func checkWithinRange(api frontend.API, lower frontend.Variable, upper frontend.Variable, value frontend.Variable) frontend.Variable {
-	return api.And(isLessOrEqual(api, value, upper), isLessOrEqual(api, lower, value))
+	half := new(big.Int).Rsh(api.Compiler().Field(), 1)
+	lower, upper, value = api.Add(lower, half), api.Add(upper, half), api.Add(value, half)
+	return and(api, isLessOrEqual(api, value, upper), isLessOrEqual(api, lower, value))
 }
 
-// todo: deal with dummy, in reality, n may be variable-length
+// checkOneOfSet returns 1 if value is one of the items of set, 0 otherwise. Empty items pad the
+// set to its capacity and match nothing, so the empty string is never one of the set.
 func checkOneOfSet(api frontend.API, n int, set []String, value String) frontend.Variable {
-	judge := frontend.Variable(0)
+	matches := frontend.Variable(0)
 	for i := 0; i < n; i++ {
-		judge = api.Add(judge, isEqualInterface(api, set[i], value))
+		isItem := boolNeg(api, set[i].IsEmpty(api))
+		matches = api.Add(matches, and(api, isItem, isEqualInterface(api, set[i], value)))
 	}
-	return judge
+	return boolNeg(api, api.IsZero(matches))
 }
 
 func checkTimeInRange(api frontend.API, timeRange frontend.Variable, initTime frontend.Variable, targetTime frontend.Variable) frontend.Variable {
 	//target time is within the time range of init time and target time is smaller than init time
-	return api.And(isLess(api, initTime, targetTime), isLess(api, api.Add(initTime, timeRange), targetTime))
+	return and(api, isLess(api, initTime, targetTime), isLess(api, api.Add(initTime, timeRange), targetTime))
 }
 
 func checkFormat(api frontend.API, n int, format []frontend.Variable, value String) frontend.Variable {
@@ -123,11 +176,53 @@ func checkFormat(api frontend.API, n int, format []frontend.Variable, value Stri
 	judge := frontend.Variable(1)
 	//Skip first position
 	for i := 1; i < n+1; i++ {
-		check1 := api.Select(isEqual(api, format[i-1], 1), api.And(isLess(api, api.Sub(value[i], 65), 26), isGreater(api, api.Sub(value[i], 65), 0)), 0)
-		check2 := api.Select(isEqual(api, format[i-1], 2), api.And(isLess(api, api.Sub(value[i], 97), 26), isGreater(api, api.Sub(value[i], 97), 0)), 0)
-		check3 := api.Select(isEqual(api, format[i-1], 3), api.And(isLess(api, api.Sub(value[i], 48), 10), isGreater(api, api.Sub(value[i], 48), 0)), 0)
-		check4 := api.Select(isEqual(api, format[i-1], 4), api.And(isLess(api, api.Sub(value[i], 33), 15), isGreater(api, api.Sub(value[i], 33), 0)), 0)
-		judge = api.And(judge, api.Or(api.Or(check1, check2), api.Or(check3, check4)))
+		check1 := api.Select(isEqual(api, format[i-1], 1), and(api, isLess(api, api.Sub(value[i], 65), 26), isGreater(api, api.Sub(value[i], 65), 0)), 0)
+		check2 := api.Select(isEqual(api, format[i-1], 2), and(api, isLess(api, api.Sub(value[i], 97), 26), isGreater(api, api.Sub(value[i], 97), 0)), 0)
+		check3 := api.Select(isEqual(api, format[i-1], 3), and(api, isLess(api, api.Sub(value[i], 48), 10), isGreater(api, api.Sub(value[i], 48), 0)), 0)
+		check4 := api.Select(isEqual(api, format[i-1], 4), and(api, isLess(api, api.Sub(value[i], 33), 15), isGreater(api, api.Sub(value[i], 33), 0)), 0)
+		judge = and(api, judge, api.Or(api.Or(check1, check2), api.Or(check3, check4)))
+	}
+	return judge
+}
+
+// checkRegex runs the automaton over the characters of value and returns 1 if it ends in an
+// accepting state. The DUMMY padding leaves the state unchanged, so value may have any length
+// up to its capacity.
+func checkRegex(api frontend.API, d *dfa.DFA, value String) frontend.Variable {
+	// only the characters read by some transition need a comparison
+	var used []int
+	for a := 0; a < dfa.Alphabet; a++ {
+		for q := range d.Next {
+			if d.Next[q][a] != 0 {
+				used = append(used, a)
+				break
+			}
+		}
+	}
+	state := frontend.Variable(d.Start)
+	for i := 1; i < len(value); i++ {
+		isChar := make(map[int]frontend.Variable, len(used))
+		for _, a := range used {
+			isChar[a] = isEqual(api, value[i], a)
+		}
+		// the dead state 0 adds nothing, as does a character without transition
+		next := frontend.Variable(0)
+		for q := 1; q < len(d.Next); q++ {
+			target := frontend.Variable(0)
+			for _, a := range used {
+				if d.Next[q][a] != 0 {
+					target = api.Add(target, api.Mul(isChar[a], d.Next[q][a]))
+				}
+			}
+			next = api.Add(next, api.Mul(isEqual(api, state, q), target))
+		}
+		state = api.Select(isDummy(api, value[i]), state, next)
+	}
+	judge := frontend.Variable(0)
+	for q := range d.Accept {
+		if d.Accept[q] {
+			judge = api.Add(judge, isEqual(api, state, q))
+		}
 	}
 	return judge
 }
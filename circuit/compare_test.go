@@ -0,0 +1,98 @@
+package circuit
+
+import (
+	"testing"
+
+	"github.com/Nullus-Labs/IDEA-DAC/dfa"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+type RegexCircuit struct {
+	Regex *dfa.DFA `gnark:"-"`
+	Value String
+}
+
+func (c *RegexCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(checkRegex(api, c.Regex, c.Value), 1)
+	return nil
+}
+
+func TestCheckRegex(t *testing.T) {
+	const capacity = 25
+	cases := []struct {
+		pattern string
+		valid   []string
+		invalid []string
+	}{
+		{`[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`, []string{"alice@uni.edu"}, []string{"alice@uni", "Alice@uni.edu", ""}},
+		{`\d{4}-\d{4}-\d{4}-\d{3}[\dX]`, []string{"0000-0002-1825-0097", "0000-0002-1825-009X"}, []string{"0000-0002-1825-009"}},
+		{`(PHD-)?[A-Z]{3}\d{2}`, []string{"UNI42", "PHD-UNI42"}, []string{"PHD-UNI4", "UNI42 "}},
+	}
+	for _, c := range cases {
+		d := dfa.MustCompile(c.pattern)
+		circuit := RegexCircuit{Regex: d, Value: EmptyString(capacity)}
+		for _, s := range c.valid {
+			assignment := RegexCircuit{Value: makeTestString(s, capacity)}
+			if err := test.IsSolved(&circuit, &assignment, ecc.BN254.ScalarField()); err != nil {
+				t.Errorf("%q should match %q: %v", s, c.pattern, err)
+			}
+		}
+		for _, s := range c.invalid {
+			assignment := RegexCircuit{Value: makeTestString(s, capacity)}
+			if err := test.IsSolved(&circuit, &assignment, ecc.BN254.ScalarField()); err == nil {
+				t.Errorf("%q should not match %q", s, c.pattern)
+			}
+		}
+	}
+}
+
+type enrollment struct {
+	Status String
+	Year   Integer
+}
+
+// RulesCircuit asserts that the edit from Old to New satisfies a oneOfSet and a withinRange rule
+type RulesCircuit struct {
+	Old, New enrollment
+	Set      [3]String `gnark:",public"`
+}
+
+func (c *RulesCircuit) Define(api frontend.API) error {
+	compareContent(api, c.Old, c.New, []Rule{
+		{Path: "Status", Kind: RuleOneOfSet, Set: c.Set[:]},
+		{Path: "Year", Kind: RuleWithinRange, Range: [2]frontend.Variable{0, 10}},
+	})
+	return nil
+}
+
+func TestCompareContent(t *testing.T) {
+	const capacity = 6
+	makeEnrollment := func(status string, year int64) enrollment {
+		return enrollment{Status: makeTestString(status, capacity), Year: NewInteger(year, 2)}
+	}
+	// a set holding an item twice, padded with an empty item
+	set := [3]String{makeTestString("A", capacity), makeTestString("A", capacity), EmptyString(capacity)}
+	circuit := &RulesCircuit{Old: makeEnrollment("", 0), New: makeEnrollment("", 0), Set: [3]String{EmptyString(capacity), EmptyString(capacity), EmptyString(capacity)}}
+	for _, tc := range []struct {
+		name   string
+		status string
+		year   int64
+		valid  bool
+	}{
+		{"valid", "A", 5, true},
+		// the two matches of the set must not make up for the failed range
+		{"out of range", "A", 11, false},
+		{"padding", "", 5, false},
+		{"not in the set", "B", 5, false},
+	} {
+		assignment := &RulesCircuit{Old: makeEnrollment("A", 5), New: makeEnrollment(tc.status, tc.year), Set: set}
+		err := test.IsSolved(circuit, assignment, ecc.BN254.ScalarField())
+		if tc.valid && err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		} else if !tc.valid && err == nil {
+			t.Errorf("%s: expected the edit to be rejected", tc.name)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package circuit
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+var ruleKindNames = [...]string{
+	RuleImmutable:     "immutable",
+	RuleAppendOnly:    "appendOnly",
+	RuleOneOfSet:      "oneOfSet",
+	RuleWithinRange:   "withinRange",
+	RuleTimeInRange:   "timeInRange",
+	RuleCertainFormat: "certainFormat",
+	RuleMerkleSet:     "merkleSet",
+	RuleRegexFormat:   "regexFormat",
+}
+
+func (k RuleKind) String() string {
+	if k < 0 || int(k) >= len(ruleKindNames) {
+		return fmt.Sprintf("RuleKind(%d)", int(k))
+	}
+	return ruleKindNames[k]
+}
+
+// String names the rule after its kind and field, e.g. oneOfSet(status)
+func (r Rule) String() string {
+	return fmt.Sprintf("%s(%s)", r.Kind, r.Path)
+}
+
+// RuleResult is the outcome of one rule of an edit
+type RuleResult struct {
+	Rule   string
+	Passed bool
+}
+
+func (r RuleResult) String() string {
+	if r.Passed {
+		return r.Rule + ": passed"
+	}
+	return r.Rule + ": failed"
+}
+
+// DiagnoseEdit solves the edit circuit with assignment in the test engine on the scalar field of
+// curve and returns the outcome of each of its rules, in order, together with the solver error if
+// any. The circuit itself only asserts that all rules hold, which does not tell which field edit
+// was rejected.
+func DiagnoseEdit(curve ecc.ID, circuit frontend.Circuit, assignment frontend.Circuit) ([]RuleResult, error) {
+	var res []RuleResult
+	report := func(api frontend.API) frontend.API {
+		o := optionsOf(api)
+		o.reporter = func(r RuleResult) {
+			res = append(res, r)
+		}
+		return o
+	}
+	// Constant variables let compareContent read the outcome of the rules
+	err := test.IsSolved(circuit, assignment, curve.ScalarField(), test.SetAllVariablesAsConstants(), test.WithApiWrapper(report))
+	return res, err
+}
+
+// reportRule passes the outcome of a rule to the reporter of api, set while DiagnoseEdit runs
+func reportRule(api frontend.API, rule Rule, passed frontend.Variable) {
+	reporter := optionsOf(api).reporter
+	if reporter == nil {
+		return
+	}
+	if v, ok := api.Compiler().ConstantValue(passed); ok {
+		reporter(RuleResult{Rule: rule.String(), Passed: v.IsUint64() && v.Uint64() == 1})
+	}
+}
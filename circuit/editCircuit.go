@@ -0,0 +1,45 @@
+package circuit
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/signature/eddsa"
+)
+
+// EditCheck is the schema-driven counterpart of EditCheckPhd: oldContent and newContent are
+// any record struct built from Integer, String, slices and nested structs, and limit supplies
+// the rule bound to each of their fields. Signature is the one by IssuerKey of the issued record:
+// OldRecord at version 0, e.g. with EmptyHistory() for a circuit not keeping the lineage of its
+// records, and the record of digest OldHistory.Issued past it.
+func EditCheck(api frontend.API, OldRecord Record, NewRecord Record, OldHistory History, IssuerKey eddsa.PublicKey, Signature eddsa.Signature, limit Limit, commitedKey frontend.Variable, oldContent interface{}, newContent interface{}, Key frontend.Variable, Blinding frontend.Variable) {
+	contentCheck(api, commitedKey, Key, Blinding, oldContent, newContent, OldRecord, NewRecord, issuedDigest(api, OldHistory, OldRecord), IssuerKey, Signature, limit.Rules(api))
+}
+
+func contentCheck(api frontend.API, commitedKey frontend.Variable, Key frontend.Variable, Blinding frontend.Variable, oldContent interface{}, newContent interface{}, oldRecord Record, newRecord Record, issued frontend.Variable, issuerKey eddsa.PublicKey, signature eddsa.Signature, rules []Rule) {
+	compareContent(api, oldContent, newContent, rules)
+	api.AssertIsEqual(commitedKey, commit(api, Key, Blinding))
+	checkIssuerSignature(api, issuerKey, signature, issued)
+
+	encodedOldContent := encodeContent(api, oldContent)
+	checkRecord(api, Key, oldRecord, encodedOldContent)
+
+	// under the same nonce the keystreams cancel out, c_old - c_new = m_old - m_new
+	api.AssertIsDifferent(oldRecord.Nonce, newRecord.Nonce)
+	encodedNewContent := encodeContent(api, newContent)
+	checkRecord(api, Key, newRecord, encodedNewContent)
+}
+
+func compareContent(api frontend.API, oldContent interface{}, newContent interface{}, rules []Rule) {
+	all := frontend.Variable(1)
+	for _, rule := range rules {
+		passed := rule.check(api, oldContent, newContent)
+		reportRule(api, rule, passed)
+		all = and(api, all, passed)
+	}
+	api.AssertIsEqual(all, 1)
+}
+
+func encodeContent(api frontend.API, content interface{}) []frontend.Variable {
+	var mergeList [][]frontend.Variable
+	mergeList = encodeDict(api, toDict(api, content, MaxKeyLen), mergeList)
+	return batchMerge(api, mergeList)
+}
@@ -2,38 +2,33 @@ package circuit
 
 import (
 	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/signature/eddsa"
 )
 
-func EditCheckPhd(api frontend.API, OldRecord []frontend.Variable, NewRecord []frontend.Variable, limit PhdLimit, commitedKey frontend.Variable, oldContent PhDProfile, newContent PhDProfile, Key frontend.Variable) {
-	contentCheckPhd(api, commitedKey, Key, oldContent, newContent, OldRecord, NewRecord, limit)
+func EditCheckPhd(api frontend.API, OldRecord Record, NewRecord Record, OldHistory History, IssuerKey eddsa.PublicKey, Signature eddsa.Signature, limit PhdLimit, commitedKey frontend.Variable, oldContent PhDProfile, newContent PhDProfile, Key frontend.Variable, Blinding frontend.Variable) {
+	contentCheckPhd(api, commitedKey, Key, Blinding, oldContent, newContent, OldRecord, NewRecord, issuedDigest(api, OldHistory, OldRecord), IssuerKey, Signature, limit)
 }
 
-func contentCheckPhd(api frontend.API, commitedKey frontend.Variable, Key frontend.Variable, oldContent PhDProfile, newContent PhDProfile, oldRecord []frontend.Variable, newRecord []frontend.Variable, limit PhdLimit) {
+func contentCheckPhd(api frontend.API, commitedKey frontend.Variable, Key frontend.Variable, Blinding frontend.Variable, oldContent PhDProfile, newContent PhDProfile, oldRecord Record, newRecord Record, issued frontend.Variable, issuerKey eddsa.PublicKey, signature eddsa.Signature, limit PhdLimit) {
 	compareContentPhd(api, oldContent, newContent, limit)
-	api.AssertIsEqual(commitedKey, commit(api, Key))
+	api.AssertIsEqual(commitedKey, commit(api, Key, Blinding))
+	checkIssuerSignature(api, issuerKey, signature, issued)
 
 	encodedOldContent := encodePhdProfile(api, oldContent)
-	assertArrayEqualWithUnequalLength(api, oldRecord, encrypt(api, Key, encodedOldContent))
+	checkRecord(api, Key, oldRecord, encodedOldContent)
 
+	// under the same nonce the keystreams cancel out, c_old - c_new = m_old - m_new
+	api.AssertIsDifferent(oldRecord.Nonce, newRecord.Nonce)
 	encodedNewContent := encodePhdProfile(api, newContent)
-	assertArrayEqualWithUnequalLength(api, newRecord, encrypt(api, Key, encodedNewContent))
+	checkRecord(api, Key, newRecord, encodedNewContent)
 }
 
 func compareContentPhd(api frontend.API, oldContent PhDProfile, newContent PhDProfile, limit PhdLimit) {
-	sum := frontend.Variable(0)
-	sum = api.Add(sum, checkAppendOnlyPhd(api, oldContent.Publications[:], newContent.Publications[:]))
-	sum = api.Add(sum, checkOneOfSet(api, 4, limit.StatusSet[:], newContent.Status))
-	sum = api.Add(sum, checkWithinRange(api, limit.YearRange[0], limit.YearRange[1], newContent.ProgramYear.X))
-	sum = api.Add(sum, checkTimeInRange(api, api.Mul(limit.TimeMinRange.X, OneYearUnix), newContent.Duration.Start.X, newContent.Duration.End.X))
-	sum = api.Add(sum, checkFormat(api, 5, limit.Format, newContent.StudentID))
-	sum = api.Add(sum, isEqualString(api, oldContent.StudentID, newContent.StudentID))
-	api.AssertIsEqual(sum, frontend.Variable(6))
+	compareContent(api, oldContent, newContent, limit.Rules(api))
 }
 
 func encodePhdProfile(api frontend.API, profile PhDProfile) []frontend.Variable {
-	var mergeList [][]frontend.Variable
-	mergeList = encodeDict(api, toDict(api, profile, MaxKeyLen), mergeList)
-	return batchMerge(api, mergeList)
+	return encodeContent(api, profile)
 }
 
 func assertArrayEqualWithUnequalLength(api frontend.API, a []frontend.Variable, b []frontend.Variable) {
@@ -0,0 +1,230 @@
+package circuit
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/signature/eddsa"
+	"github.com/consensys/gnark/test"
+)
+
+const (
+	testMaxRecLen = 12
+	testMaxStrLen = 12
+	testMaxTests  = 3
+)
+
+type CovidEditCircuit struct {
+	OldRecord    Record          `gnark:",public"`
+	NewRecord    Record          `gnark:",public"`
+	IssuerKey    eddsa.PublicKey `gnark:",public"`
+	Signature    eddsa.Signature
+	Limit        CovidLimit        `gnark:",public"`
+	CommittedKey frontend.Variable `gnark:",public"`
+	OldContent   CovidRecord
+	NewContent   CovidRecord
+	Key          frontend.Variable
+	Blinding     frontend.Variable
+	Primitive    Primitive `gnark:"-"`
+}
+
+func (c *CovidEditCircuit) Define(api frontend.API) error {
+	if c.Primitive != nil {
+		api = WithPrimitive(api, c.Primitive)
+	}
+	EditCheck(api, c.OldRecord, c.NewRecord, EmptyHistory(), c.IssuerKey, c.Signature, c.Limit, c.CommittedKey, c.OldContent, c.NewContent, c.Key, c.Blinding)
+	return nil
+}
+
+func makeTestString(s string, capacity int) String {
+	res := make(String, capacity)
+	res[0] = len(s)
+	for i := 1; i < capacity; i++ {
+		if i <= len(s) {
+			res[i] = int(s[i-1])
+		} else {
+			res[i] = DUMMY
+		}
+	}
+	return res
+}
+
+func makeTestRecord(cipher Cipher, json string, key *fr.Element, nonce int64, capacity int) *Ciphertext {
+	enc, err := cipher.Encrypt([]byte(json), key.BigInt(new(big.Int)), big.NewInt(nonce), capacity)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+func makeTestCovidRecord(vaccine string, dosage int, results []string, status string) CovidRecord {
+	res := CovidRecord{
+		LatestVaccine: Vaccine{
+			VaccineType: makeTestString(vaccine, testMaxStrLen),
+			Dosage:      Integer{X: dosage, MaxDigit: 1},
+		},
+		CovidTest:              make([]CovidTest, testMaxTests),
+		CovidTestNumber:        makeTestString("CT123", 6),
+		MedicalInsuranceStatus: makeTestString(status, testMaxStrLen),
+		CoverageEndDate:        Integer{X: 1700000000, MaxDigit: 10},
+	}
+	for i := range res.CovidTest {
+		res.CovidTest[i] = CovidTest{TestDate: Integer{X: 0, MaxDigit: 10}, Result: makeTestString("", testMaxStrLen)}
+	}
+	for i, result := range results {
+		res.CovidTest[i] = CovidTest{TestDate: Integer{X: 1650000000 + i, MaxDigit: 10}, Result: makeTestString(result, testMaxStrLen)}
+	}
+	return res
+}
+
+func newCovidEditCircuit() CovidEditCircuit {
+	limit := CovidLimit{
+		VaccineTypeSet:            []String{makeTestString("", testMaxStrLen), makeTestString("", testMaxStrLen)},
+		MedicalInsuranceStatusSet: []String{makeTestString("", testMaxStrLen), makeTestString("", testMaxStrLen)},
+		DosageMax:                 0,
+		CoverageMaxEndDate:        Integer{X: 0, MaxDigit: 10},
+		Format:                    []frontend.Variable{0, 0, 0, 0, 0},
+	}
+	return CovidEditCircuit{
+		OldRecord:    EmptyRecord(testMaxRecLen),
+		NewRecord:    EmptyRecord(testMaxRecLen),
+		IssuerKey:    EmptyIssuerKey(),
+		Signature:    EmptySignature(),
+		Limit:        limit,
+		CommittedKey: 0,
+		OldContent:   makeTestCovidRecord("", 0, nil, ""),
+		NewContent:   makeTestCovidRecord("", 0, nil, ""),
+		Key:          0,
+		Blinding:     0,
+	}
+}
+
+func covidEditAssignment(p Primitive, newDosage int, newResults []string, newJSON string) CovidEditCircuit {
+	oldJSON := `{"LatestVaccine":{"VaccineType":"Pfizer","Dosage":2},"CovidTest":[{"TestDate":1650000000,"Result":"Negative"}],"CovidTestNumber":"CT123","MedicalInsuranceStatus":"Active","CoverageEndDate":1700000000}`
+	key := new(fr.Element).SetUint64(42)
+	res := newCovidEditCircuit()
+	res.Limit.VaccineTypeSet = []String{makeTestString("Pfizer", testMaxStrLen), makeTestString("Moderna", testMaxStrLen)}
+	res.Limit.MedicalInsuranceStatusSet = []String{makeTestString("Active", testMaxStrLen), makeTestString("Expired", testMaxStrLen)}
+	res.Limit.DosageMax = 4
+	res.Limit.CoverageMaxEndDate = Integer{X: 1800000000, MaxDigit: 10}
+	res.Limit.Format = []frontend.Variable{1, 1, 3, 3, 3}
+	res.Key = key.BigInt(new(big.Int))
+	res.Blinding = big.NewInt(4242)
+	cipher := Cipher{ecc.BN254, p}
+	res.CommittedKey = cipher.CommitKey(res.Key.(*big.Int), res.Blinding.(*big.Int))
+	res.OldContent = makeTestCovidRecord("Pfizer", 2, []string{"Negative"}, "Active")
+	res.NewContent = makeTestCovidRecord("Moderna", newDosage, newResults, "Active")
+	oldRecord := makeTestRecord(cipher, oldJSON, key, 7, testMaxRecLen)
+	res.OldRecord = oldRecord.Record()
+	res.NewRecord = makeTestRecord(cipher, newJSON, key, 8, testMaxRecLen).Record()
+
+	issuer, err := NewIssuer(ecc.BN254)
+	if err != nil {
+		panic(err)
+	}
+	sig, err := cipher.Sign(issuer, oldRecord)
+	if err != nil {
+		panic(err)
+	}
+	res.IssuerKey.Assign(IssuerCurve(ecc.BN254), issuer.Public().Bytes())
+	res.Signature.Assign(IssuerCurve(ecc.BN254), sig)
+	return res
+}
+
+func TestEditCheckCovid(t *testing.T) {
+	circuit := newCovidEditCircuit()
+
+	newJSON := `{"LatestVaccine":{"VaccineType":"Moderna","Dosage":3},"CovidTest":[{"TestDate":1650000000,"Result":"Negative"},{"TestDate":1650000001,"Result":"Positive"}],"CovidTestNumber":"CT123","MedicalInsuranceStatus":"Active","CoverageEndDate":1700000000}`
+	valid := covidEditAssignment(MimcPrimitive, 3, []string{"Negative", "Positive"}, newJSON)
+	if err := test.IsSolved(&circuit, &valid, ecc.BN254.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the tag does not match the new record
+	forged := covidEditAssignment(MimcPrimitive, 3, []string{"Negative", "Positive"}, newJSON)
+	forged.NewRecord.Tag = 1
+	if err := test.IsSolved(&circuit, &forged, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected a forged tag to be rejected")
+	}
+
+	// the new record reuses the nonce of the old one
+	reused := covidEditAssignment(MimcPrimitive, 3, []string{"Negative", "Positive"}, newJSON)
+	reused.NewRecord = makeTestRecord(Cipher{ecc.BN254, MimcPrimitive}, newJSON, new(fr.Element).SetUint64(42), 7, testMaxRecLen).Record()
+	if err := test.IsSolved(&circuit, &reused, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected a reused nonce to be rejected")
+	}
+
+	// the old record was signed by another issuer
+	unsigned := covidEditAssignment(MimcPrimitive, 3, []string{"Negative", "Positive"}, newJSON)
+	unsigned.IssuerKey = valid.IssuerKey
+	if err := test.IsSolved(&circuit, &unsigned, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected the signature of another issuer to be rejected")
+	}
+
+	// dosage exceeds DosageMax
+	newJSON = `{"LatestVaccine":{"VaccineType":"Moderna","Dosage":5},"CovidTest":[{"TestDate":1650000000,"Result":"Negative"},{"TestDate":1650000001,"Result":"Positive"}],"CovidTestNumber":"CT123","MedicalInsuranceStatus":"Active","CoverageEndDate":1700000000}`
+	invalid := covidEditAssignment(MimcPrimitive, 5, []string{"Negative", "Positive"}, newJSON)
+	if err := test.IsSolved(&circuit, &invalid, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected the dosage rule to reject the edit")
+	}
+
+	results, err := DiagnoseEdit(ecc.BN254, &circuit, &invalid)
+	if err == nil {
+		t.Fatal("expected the dosage rule to reject the edit")
+	}
+	rules := invalid.Limit.Rules(nil)
+	if len(results) != len(rules) {
+		t.Fatalf("expected %d results, got %v", len(rules), results)
+	}
+	for i, r := range results {
+		if r.Rule != rules[i].String() || r.Passed != (r.Rule != "withinRange(LatestVaccine.Dosage)") {
+			t.Errorf("unexpected result %v", r)
+		}
+	}
+
+	// a compilation running meanwhile does not report its rules to the diagnosis
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		compiled := newCovidEditCircuit()
+		if _, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &compiled); err != nil {
+			t.Error(err)
+		}
+	}()
+	results, _ = DiagnoseEdit(ecc.BN254, &circuit, &valid)
+	wg.Wait()
+	if len(results) != len(rules) {
+		t.Fatalf("expected %d results, got %v", len(rules), results)
+	}
+}
+
+func TestEditCheckPoseidon(t *testing.T) {
+	newJSON := `{"LatestVaccine":{"VaccineType":"Moderna","Dosage":3},"CovidTest":[{"TestDate":1650000000,"Result":"Negative"},{"TestDate":1650000001,"Result":"Positive"}],"CovidTestNumber":"CT123","MedicalInsuranceStatus":"Active","CoverageEndDate":1700000000}`
+	circuit := newCovidEditCircuit()
+	circuit.Primitive = PoseidonPrimitive
+	valid := covidEditAssignment(PoseidonPrimitive, 3, []string{"Negative", "Positive"}, newJSON)
+	if err := test.IsSolved(&circuit, &valid, ecc.BN254.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+	// the record is encrypted with MiMC
+	mimc := covidEditAssignment(MimcPrimitive, 3, []string{"Negative", "Positive"}, newJSON)
+	if err := test.IsSolved(&circuit, &mimc, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected a record of another primitive to be rejected")
+	}
+
+	for _, p := range []Primitive{MimcPrimitive, PoseidonPrimitive} {
+		circuit := newCovidEditCircuit()
+		circuit.Primitive = p
+		cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &circuit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("%s: %d constraints", p, cs.GetNbConstraints())
+	}
+}
@@ -2,28 +2,77 @@ package circuit
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
+	"strings"
 
 	"github.com/consensys/gnark/frontend"
 )
 
+// Integer is a JSON number of at most MaxDigit digits. A Signed one may be negative, written
+// with a leading '-'. With a Scale, X is the number times 10^Scale written with exactly Scale
+// digits after the decimal point, e.g. X = -1250 for -12.50 with Scale 2, or without its trailing
+// zeros with WithCanonicalJSON.
 type Integer struct {
 	X        frontend.Variable
 	MaxDigit int
+	Signed   bool
+	Scale    int
 }
 
-type String []frontend.Variable // Each var is a UTF-8 ASCII character
+type String []frontend.Variable // Each var is a byte of the UTF-8 encoding
 
 type Array []IsEmptyInterface // Each element is a Integer or String
 
+// Bool is a JSON true or false, X being 1 or 0
+type Bool struct {
+	X frontend.Variable
+}
+
+// Null is the JSON null
+type Null struct{}
+
+// OptionalInteger, OptionalString and OptionalBool are encoded as null when IsNull is 1 and
+// as Value otherwise, Value being then left empty
+type OptionalInteger struct {
+	IsNull frontend.Variable
+	Value  Integer
+}
+
+type OptionalString struct {
+	IsNull frontend.Variable
+	Value  String
+}
+
+type OptionalBool struct {
+	IsNull frontend.Variable
+	Value  Bool
+}
+
+// optional is implemented by the Optional types
+type optional interface {
+	null() frontend.Variable
+	value() interface{}
+}
+
+func (x OptionalInteger) null() frontend.Variable { return x.IsNull }
+func (x OptionalInteger) value() interface{}      { return x.Value }
+func (x OptionalString) null() frontend.Variable  { return x.IsNull }
+func (x OptionalString) value() interface{}       { return x.Value }
+func (x OptionalBool) null() frontend.Variable    { return x.IsNull }
+func (x OptionalBool) value() interface{}         { return x.Value }
+
 type IsEmptyInterface interface {
 	IsEmpty(api frontend.API) frontend.Variable
 }
 
-// Each var is a UTF-8 ASCII character
+// Dict is a JSON object, its keys in order. A value whose omitEmpty is set is left out with its
+// key when empty, see isEmptyValue.
 type Dict struct {
-	keys   []String
-	values []interface{}
+	keys      []String
+	names     []string // the keys, sorting the members of a canonical dict
+	values    []interface{}
+	omitEmpty []bool
 }
 
 func (x Integer) IsEmpty(api frontend.API) frontend.Variable {
@@ -42,60 +91,187 @@ func (x CovidTest) IsEmpty(api frontend.API) frontend.Variable {
 	return x.Result.IsEmpty(api)
 }
 
-// Decimal Representation
+// Decimal Representation, see Integer for the sign and the decimal point
 func encodeNumber(api frontend.API, x Integer, mergeList [][]frontend.Variable) [][]frontend.Variable {
+	if isCanonical(api) && (x.MaxDigit > 15 || x.Scale > 6) {
+		panic("Invalid Integer for canonical JSON")
+	}
+	if !x.Signed && x.Scale == 0 {
+		return append(mergeList, encodeDigits(api, x.X, x.MaxDigit))
+	}
+	if x.Scale < 0 || x.Scale >= x.MaxDigit {
+		panic("Invalid Scale")
+	}
+	abs := x.X
+	if x.Signed {
+		sign, err := api.Compiler().NewHint(getSign, 1, x.X)
+		if err != nil {
+			panic(err)
+		}
+		api.AssertIsBoolean(sign[0])
+		abs = api.Select(sign[0], api.Neg(x.X), x.X)
+		// no -0
+		api.AssertIsEqual(api.Mul(sign[0], api.IsZero(abs)), 0)
+		mergeList = append(mergeList, []frontend.Variable{sign[0], api.Select(sign[0], int('-'), DUMMY)})
+	}
+	if x.Scale == 0 {
+		return append(mergeList, encodeDigits(api, abs, x.MaxDigit))
+	}
+	// abs = integral * 10^Scale + fraction, the fraction written with exactly Scale digits
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(x.Scale)), nil)
+	parts, err := api.Compiler().NewHint(idiv, 2, abs, unit)
+	if err != nil {
+		panic(err)
+	}
+	integral, fraction := parts[0], parts[1]
+	api.AssertIsEqual(abs, api.Add(api.Mul(integral, unit), fraction))
+	// the digits of 10^Scale + fraction are 1 followed by those of the fraction
+	digits, err := api.Compiler().NewHint(getDecimal, x.Scale+2, x.Scale+1, api.Add(fraction, unit))
+	if err != nil {
+		panic(err)
+	}
+	res := make([]frontend.Variable, x.Scale+1)
+	res[0] = x.Scale
+	total := frontend.Variable(0)
+	allValid := frontend.Variable(0)
+	for i := 0; i < x.Scale; i++ {
+		allValid = api.Add(allValid, isDigit(api, digits[i+2]))
+		total = api.Add(api.Mul(total, 10), digits[i+2])
+		res[i+1] = api.Add(digits[i+2], 48)
+	}
+	api.AssertIsEqual(allValid, x.Scale)
+	api.AssertIsEqual(total, fraction)
+	mergeList = append(mergeList, encodeDigits(api, integral, x.MaxDigit-x.Scale))
+	dot := frontend.Variable(1)
+	if isCanonical(api) {
+		// the trailing zeros of the fraction are left out, with the point of a whole number
+		kept := frontend.Variable(0)
+		res[0] = 0
+		for i := x.Scale - 1; i >= 0; i-- {
+			kept = api.Or(kept, boolNeg(api, api.IsZero(digits[i+2])))
+			res[i+1] = api.Select(kept, res[i+1], DUMMY)
+			res[0] = api.Add(res[0], kept)
+		}
+		dot = kept
+	}
+	mergeList = append(mergeList, []frontend.Variable{dot, api.Select(dot, int('.'), DUMMY)})
+	return append(mergeList, res)
+}
+
+// encodeDigits returns the decimal digits of the non-negative x, at most maxDigit
+func encodeDigits(api frontend.API, x frontend.Variable, maxDigit int) []frontend.Variable {
 	// x = 101
 	// xx[3] = [1, 0, 1]  // Hint
 	// 1 * 100 + 0 * 10 + 1 * 1 = 101
 	// yy[3] = [34, 33, 34] // Json
-	decimal, err := api.Compiler().NewHint(getDecimal, x.MaxDigit+1, []frontend.Variable{x.MaxDigit, x.X}...)
+	decimal, err := api.Compiler().NewHint(getDecimal, maxDigit+1, []frontend.Variable{maxDigit, x}...)
 	if err != nil {
 		panic(err)
 	}
 
 	// RangeProof 0-9
 	allValid := frontend.Variable(0)
-	for i := 0; i < x.MaxDigit; i++ {
-		valid := api.Add(withinBinary(api, decimal[i+1], 3), isEqual(api, decimal[i+1], 8), isEqual(api, decimal[i+1], 9))
-		allValid = api.Add(allValid, valid)
+	for i := 0; i < maxDigit; i++ {
+		allValid = api.Add(allValid, isDigit(api, decimal[i+1]))
 	}
-	api.AssertIsEqual(allValid, x.MaxDigit)
+	api.AssertIsEqual(allValid, maxDigit)
 	// Check the decimal representation is correct
 	total := frontend.Variable(0)
 	remLen := decimal[0]
-	isEnd := make([]frontend.Variable, x.MaxDigit)
-	for i := 0; i < x.MaxDigit; i++ {
+	isEnd := make([]frontend.Variable, maxDigit)
+	for i := 0; i < maxDigit; i++ {
 		isEnd[i] = api.IsZero(remLen)
 		total = api.Select(isEnd[i], total, api.Mul(total, 10))
-		api.AssertIsEqual(api.And(isEnd[i], boolNeg(api, api.IsZero(decimal[i+1]))), 0)
+		api.AssertIsEqual(and(api, isEnd[i], boolNeg(api, api.IsZero(decimal[i+1]))), 0)
 		total = api.Add(total, decimal[i+1])
 		remLen = api.Select(isEnd[i], remLen, api.Sub(remLen, 1))
 	}
-	api.AssertIsEqual(total, x.X)
+	api.AssertIsEqual(total, x)
 	res := make([]frontend.Variable, len(decimal))
 	res[0] = decimal[0]
 	for i := 1; i < len(decimal); i++ {
 		res[i] = api.Select(isEnd[i-1], DUMMY, api.Add(decimal[i], 48))
 	}
-	mergeList = append(mergeList, res)
-	return mergeList
+	return res
 }
 
-// a-z A-Z ASCII
+// isDigit returns 1 if d is within 0-9
+func isDigit(api frontend.API, d frontend.Variable) frontend.Variable {
+	return api.Add(withinBinary(api, d, 3), isEqual(api, d, 8), isEqual(api, d, 9))
+}
+
+// UTF-8 bytes, escaped as encoding/json escapes them, see escapeString
 func encodeString(api frontend.API, str String, mergeList [][]frontend.Variable) [][]frontend.Variable {
-	rangeCheckString(api, str)
-	legitimateCheck(api, str)
 	mergeList = append(mergeList, []frontend.Variable{1, int('"')})
-	mergeList = append(mergeList, str)
+	if s, ok := constantString(api, str); ok {
+		escaped := escapeJSON(s)
+		if isCanonical(api) {
+			escaped = escapeCanonical(s)
+		}
+		part := make([]frontend.Variable, len(escaped)+1)
+		part[0] = len(escaped)
+		for i, c := range escaped {
+			part[i+1] = int(c)
+		}
+		mergeList = append(mergeList, part)
+	} else {
+		rangeCheckString(api, str)
+		legitimateCheck(api, str)
+		mergeList = append(mergeList, escapeString(api, str)...)
+	}
 	mergeList = append(mergeList, []frontend.Variable{1, int('"')})
 	return mergeList
 }
 
+// true or false, padded to the length of false
+func encodeBool(api frontend.API, x Bool, mergeList [][]frontend.Variable) [][]frontend.Variable {
+	api.AssertIsBoolean(x.X)
+	t, f := "true", "false"
+	res := make([]frontend.Variable, len(f)+1)
+	res[0] = api.Sub(len(f), x.X)
+	for i := 0; i < len(f); i++ {
+		c := frontend.Variable(DUMMY)
+		if i < len(t) {
+			c = int(t[i])
+		}
+		res[i+1] = api.Select(x.X, c, int(f[i]))
+	}
+	return append(mergeList, res)
+}
+
+func encodeNull(api frontend.API, mergeList [][]frontend.Variable) [][]frontend.Variable {
+	return append(mergeList, []frontend.Variable{4, int('n'), int('u'), int('l'), int('l')})
+}
+
+// null if isNull is 1, value otherwise
+func encodeOptional(api frontend.API, isNull frontend.Variable, value interface{}, mergeList [][]frontend.Variable) [][]frontend.Variable {
+	api.AssertIsBoolean(isNull)
+	mergeList = append(mergeList, hideEncoding(api, isNull, encodeInterface(api, value, nil))...)
+	return append(mergeList, hideEncoding(api, boolNeg(api, isNull), encodeNull(api, nil))...)
+}
+
+// hideEncoding empties the parts of an encoding if hidden is 1
+func hideEncoding(api frontend.API, hidden frontend.Variable, mergeList [][]frontend.Variable) [][]frontend.Variable {
+	for j := 0; j < len(mergeList); j++ {
+		mergeList[j][0] = api.Select(hidden, 0, mergeList[j][0])
+		for k := 1; k < len(mergeList[j]); k++ {
+			mergeList[j][k] = api.Select(hidden, DUMMY, mergeList[j][k])
+		}
+	}
+	return mergeList
+}
+
 func encodeInterface(api frontend.API, in interface{}, mergeList [][]frontend.Variable) [][]frontend.Variable {
 	if v, ok := in.(Integer); ok {
 		return encodeNumber(api, v, mergeList)
 	} else if v, ok := in.(String); ok {
 		return encodeString(api, v, mergeList)
+	} else if v, ok := in.(Bool); ok {
+		return encodeBool(api, v, mergeList)
+	} else if _, ok := in.(Null); ok {
+		return encodeNull(api, mergeList)
+	} else if v, ok := in.(optional); ok {
+		return encodeOptional(api, v.null(), v.value(), mergeList)
 	} else if v, ok := in.(Array); ok {
 		return encodeArray(api, v, mergeList)
 	} else {
@@ -120,36 +296,78 @@ func encodeArray(api frontend.API, arr Array, mergeList [][]frontend.Variable) [
 		}
 		isEmpty := arr[i].IsEmpty(api)
 		newMergeList = encodeInterface(api, arr[i], newMergeList)
-		for j := 0; j < len(newMergeList); j++ {
-			newMergeList[j][0] = api.Select(isEmpty, 0, newMergeList[j][0])
-			for k := 1; k < len(newMergeList[j]); k++ {
-				newMergeList[j][k] = api.Select(isEmpty, DUMMY, newMergeList[j][k])
-			}
-		}
-		mergeList = append(mergeList, newMergeList...)
+		mergeList = append(mergeList, hideEncoding(api, isEmpty, newMergeList)...)
 	}
 	mergeList = append(mergeList, []frontend.Variable{1, int(']')})
 	return mergeList
 }
 
-// Ordered Dict by key
+// encodeDict writes the members of dict in order, sorted by key with WithCanonicalJSON
 func encodeDict(api frontend.API, dict Dict, mergeList [][]frontend.Variable) [][]frontend.Variable {
 	if len(dict.keys) != len(dict.values) {
 		panic("Invalid Dict")
 	}
+	order := make([]int, len(dict.keys))
+	for i := range order {
+		order[i] = i
+	}
+	if isCanonical(api) {
+		order = canonicalOrder(dict)
+	}
 	mergeList = append(mergeList, []frontend.Variable{1, int('{')})
-	for i := 0; i < len(dict.keys); i++ {
-		if i != 0 {
-			mergeList = append(mergeList, []frontend.Variable{1, int(',')})
+	// written is 1 once a member is written, a comma then preceding the next one
+	written := frontend.Variable(0)
+	for n, i := range order {
+		omitted := frontend.Variable(0)
+		if dict.omitEmpty != nil && dict.omitEmpty[i] {
+			omitted = isEmptyValue(api, dict.values[i])
+		}
+		if n != 0 {
+			comma := and(api, written, boolNeg(api, omitted))
+			mergeList = append(mergeList, []frontend.Variable{comma, api.Select(comma, int(','), DUMMY)})
 		}
-		mergeList = encodeString(api, dict.keys[i], mergeList)
-		mergeList = append(mergeList, []frontend.Variable{1, int(':')})
-		mergeList = encodeInterface(api, dict.values[i], mergeList)
+		var member [][]frontend.Variable
+		member = encodeString(api, dict.keys[i], member)
+		member = append(member, []frontend.Variable{1, int(':')})
+		member = encodeInterface(api, dict.values[i], member)
+		mergeList = append(mergeList, hideEncoding(api, omitted, member)...)
+		written = api.Or(written, boolNeg(api, omitted))
 	}
 	mergeList = append(mergeList, []frontend.Variable{1, int('}')})
 	return mergeList
 }
 
+// isEmptyValue returns 1 if in is left out by omitempty as encoding/json leaves out false, 0,
+// "", null and empty arrays. Structs are never empty.
+func isEmptyValue(api frontend.API, in interface{}) frontend.Variable {
+	switch v := in.(type) {
+	case Integer:
+		return v.IsEmpty(api)
+	case String:
+		return v.IsEmpty(api)
+	case Bool:
+		return api.IsZero(v.X)
+	case Null:
+		return 1
+	case optional:
+		return v.null()
+	case Array:
+		return isEmptyArray(api, v)
+	}
+	if reflect.TypeOf(in).Kind() == reflect.Slice {
+		return isEmptyArray(api, toArray(api, in))
+	}
+	return 0
+}
+
+func isEmptyArray(api frontend.API, arr Array) frontend.Variable {
+	res := frontend.Variable(1)
+	for i := range arr {
+		res = and(api, res, arr[i].IsEmpty(api))
+	}
+	return res
+}
+
 func toDict(api frontend.API, s interface{}, keyCapacity int) Dict {
 	v := reflect.ValueOf(s)
 	t := v.Type()
@@ -159,21 +377,50 @@ func toDict(api frontend.API, s interface{}, keyCapacity int) Dict {
 	}
 	dict := Dict{}
 	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		dict.keys = append(dict.keys, toString(api, t.Field(i).Name, keyCapacity))
-		dict.values = append(dict.values, field.Interface())
+		key, omitEmpty, ok := jsonKey(t.Field(i))
+		if !ok {
+			continue
+		}
+		dict.keys = append(dict.keys, toString(api, key, keyCapacity))
+		dict.names = append(dict.names, key)
+		dict.values = append(dict.values, v.Field(i).Interface())
+		dict.omitEmpty = append(dict.omitEmpty, omitEmpty)
 	}
 	return dict
 }
 
+// jsonKey returns the key of a struct field as encoding/json names it, the zkjson tag taking
+// precedence over the json one, and whether the field is tagged omitempty. ok is false for a
+// field tagged "-".
+func jsonKey(f reflect.StructField) (key string, omitEmpty bool, ok bool) {
+	tag, found := f.Tag.Lookup("zkjson")
+	if !found {
+		tag = f.Tag.Get("json")
+	}
+	if tag == "-" {
+		return "", false, false
+	}
+	key = tag
+	if i := strings.Index(tag, ","); i >= 0 {
+		key = tag[:i]
+		for _, option := range strings.Split(tag[i+1:], ",") {
+			omitEmpty = omitEmpty || option == "omitempty"
+		}
+	}
+	if key == "" {
+		key = f.Name
+	}
+	return key, omitEmpty, true
+}
+
 func toString(api frontend.API, s string, capacity int) String {
 	if capacity < len(s) {
 		panic("Invalid Capacity")
 	}
 	res := make(String, 0, capacity+1)
 	res = append(res, len(s))
-	for _, c := range s {
-		res = append(res, int(c))
+	for i := 0; i < len(s); i++ {
+		res = append(res, int(s[i]))
 	}
 	for i := len(s); i < capacity; i++ {
 		res = append(res, DUMMY)
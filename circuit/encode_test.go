@@ -0,0 +1,338 @@
+package circuit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+type thesis struct {
+	Submitted  Bool
+	Defense    Null
+	Graduation OptionalInteger
+	Advisor    OptionalString
+	Funded     OptionalBool
+}
+
+type thesisJSON struct {
+	Submitted  bool
+	Defense    interface{}
+	Graduation *int64
+	Advisor    *string
+	Funded     *bool
+}
+
+func makeThesis(in thesisJSON) thesis {
+	return thesis{
+		Submitted:  NewBool(in.Submitted),
+		Graduation: NewOptionalInteger(in.Graduation, 4),
+		Advisor:    NewOptionalString(in.Advisor, 8),
+		Funded:     NewOptionalBool(in.Funded),
+	}
+}
+
+// encodeCircuit asserts that Content is encoded as the compact JSON Expected
+type encodeCircuit struct {
+	Content  thesis
+	Expected String `gnark:",public"`
+}
+
+func (c *encodeCircuit) Define(api frontend.API) error {
+	assertEncoding(api, c.Content, c.Expected)
+	return nil
+}
+
+func assertEncoding(api frontend.API, content interface{}, expected String) {
+	encoded := encodeContent(api, content)
+	if len(encoded) < len(expected) {
+		panic("the expected JSON is longer than the capacity of the content")
+	}
+	for i := range encoded {
+		if i < len(expected) {
+			api.AssertIsEqual(encoded[i], expected[i])
+		} else {
+			api.AssertIsEqual(encoded[i], DUMMY)
+		}
+	}
+}
+
+type equalCircuit struct {
+	A, B  thesis
+	Equal frontend.Variable `gnark:",public"`
+}
+
+func (c *equalCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(isEqualInterface(api, c.A, c.B), c.Equal)
+	return nil
+}
+
+func TestEncodeBoolNull(t *testing.T) {
+	year, advisor, funded := int64(2024), "Alice", false
+	full := thesisJSON{Submitted: true, Graduation: &year, Advisor: &advisor, Funded: &funded}
+	empty := thesisJSON{}
+	for _, in := range []thesisJSON{full, empty} {
+		data, err := json.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := NewString(string(data), len(data)+1)
+		circuit := &encodeCircuit{Content: makeThesis(empty), Expected: make(String, len(expected))}
+		if err := test.IsSolved(circuit, &encodeCircuit{Content: makeThesis(in), Expected: expected}, ecc.BN254.ScalarField()); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+	}
+
+	// the encoding of another value
+	data, _ := json.Marshal(empty)
+	expected := NewString(string(data), len(data)+1)
+	wrong := makeThesis(empty)
+	wrong.Submitted = NewBool(true)
+	circuit := &encodeCircuit{Content: makeThesis(empty), Expected: make(String, len(expected))}
+	if err := test.IsSolved(circuit, &encodeCircuit{Content: wrong, Expected: expected}, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected true to be rejected for false")
+	}
+	wrong = makeThesis(empty)
+	wrong.Submitted.X = 2
+	if err := test.IsSolved(circuit, &encodeCircuit{Content: wrong, Expected: expected}, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected a non-boolean to be rejected")
+	}
+}
+
+func TestIsEqualBoolNull(t *testing.T) {
+	year, other := int64(2024), int64(2025)
+	a := makeThesis(thesisJSON{Graduation: &year})
+	// a null optional is equal whatever its value
+	hidden := makeThesis(thesisJSON{})
+	hidden.Graduation.Value = NewInteger(other, 4)
+	b := makeThesis(thesisJSON{Graduation: &other})
+	submitted := makeThesis(thesisJSON{Submitted: true})
+	for _, tc := range []struct {
+		name  string
+		a, b  thesis
+		equal int
+	}{
+		{"same", a, a, 1},
+		{"both null", makeThesis(thesisJSON{}), hidden, 1},
+		{"null and set", a, hidden, 0},
+		{"different values", a, b, 0},
+		{"different bools", makeThesis(thesisJSON{}), submitted, 0},
+	} {
+		circuit := &equalCircuit{A: makeThesis(thesisJSON{}), B: makeThesis(thesisJSON{})}
+		if err := test.IsSolved(circuit, &equalCircuit{A: tc.a, B: tc.b, Equal: tc.equal}, ecc.BN254.ScalarField()); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+	}
+}
+
+type account struct {
+	Balance Integer
+	GPA     Integer
+	Offset  Integer
+	Year    Integer
+}
+
+type accountCircuit struct {
+	Content  account
+	Expected String `gnark:",public"`
+}
+
+func (c *accountCircuit) Define(api frontend.API) error {
+	assertEncoding(api, c.Content, c.Expected)
+	return nil
+}
+
+func makeAccount(balance string, gpa string, offset int64, year int64) account {
+	return account{
+		Balance: NewDecimal(balance, 8, 2),
+		GPA:     NewDecimal(gpa, 3, 2),
+		Offset:  NewSignedInteger(offset, 3),
+		Year:    NewInteger(year, 4),
+	}
+}
+
+func TestEncodeNumber(t *testing.T) {
+	for _, tc := range []struct {
+		content account
+		json    string
+	}{
+		{makeAccount("-12.50", "3.85", -7, 2024), `{"Balance":-12.50,"GPA":3.85,"Offset":-7,"Year":2024}`},
+		{makeAccount("0.05", "0.00", 0, 0), `{"Balance":0.05,"GPA":0.00,"Offset":0,"Year":0}`},
+		{makeAccount("-123456.78", "-1.01", 999, 1), `{"Balance":-123456.78,"GPA":-1.01,"Offset":999,"Year":1}`},
+	} {
+		expected := NewString(tc.json, len(tc.json)+1)
+		circuit := &accountCircuit{Content: makeAccount("0.00", "0.00", 0, 0), Expected: make(String, len(expected))}
+		if err := test.IsSolved(circuit, &accountCircuit{Content: tc.content, Expected: expected}, ecc.BN254.ScalarField()); err != nil {
+			t.Fatalf("%s: %v", tc.json, err)
+		}
+	}
+
+	// the sign or the scale of another number
+	json := `{"Balance":-12.50,"GPA":3.85,"Offset":-7,"Year":2024}`
+	expected := NewString(json, len(json)+1)
+	circuit := &accountCircuit{Content: makeAccount("0.00", "0.00", 0, 0), Expected: make(String, len(expected))}
+	for _, wrong := range []account{makeAccount("12.50", "3.85", -7, 2024), makeAccount("-1.25", "3.85", -7, 2024)} {
+		if err := test.IsSolved(circuit, &accountCircuit{Content: wrong, Expected: expected}, ecc.BN254.ScalarField()); err == nil {
+			t.Fatalf("expected %v to be rejected", wrong.Balance.X)
+		}
+	}
+	for _, text := range []string{"-0.00", "12.5", "012.50", "1e2", "+1.00", "1234567.00"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected %s to be rejected", text)
+				}
+			}()
+			NewDecimal(text, 8, 2)
+		}()
+	}
+}
+
+type rangeCircuit struct {
+	Value        Integer
+	Lower, Upper frontend.Variable `gnark:",public"`
+	Within       frontend.Variable `gnark:",public"`
+}
+
+func (c *rangeCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(checkWithinRange(api, c.Lower, c.Upper, c.Value.X), c.Within)
+	return nil
+}
+
+func TestWithinRangeSigned(t *testing.T) {
+	for _, tc := range []struct {
+		value        string
+		lower, upper int64
+		within       int
+	}{
+		{"-12.50", -10000, 0, 1},
+		{"-12.50", 0, 400, 0},
+		{"-12.50", -1000, -1251, 0},
+		{"3.85", 0, 400, 1},
+		{"4.01", 0, 400, 0},
+		{"0.00", -1, 1, 1},
+	} {
+		circuit := &rangeCircuit{Value: NewDecimal("0.00", 8, 2)}
+		assignment := &rangeCircuit{Value: NewDecimal(tc.value, 8, 2), Lower: tc.lower, Upper: tc.upper, Within: tc.within}
+		if err := test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()); err != nil {
+			t.Fatalf("%s in [%d, %d]: %v", tc.value, tc.lower, tc.upper, err)
+		}
+	}
+}
+
+type paper struct {
+	Title String
+}
+
+type paperCircuit struct {
+	Content  paper
+	Expected String `gnark:",public"`
+}
+
+func (c *paperCircuit) Define(api frontend.API) error {
+	assertEncoding(api, c.Content, c.Expected)
+	return nil
+}
+
+// rawString is NewString without the UTF-8 check, to assign invalid bytes
+func rawString(input string, capacity int) String {
+	res := make(String, capacity)
+	res[0] = len(input)
+	for i := 1; i < capacity; i++ {
+		if i <= len(input) {
+			res[i] = int(input[i-1])
+		} else {
+			res[i] = DUMMY
+		}
+	}
+	return res
+}
+
+func TestEncodeEscapedString(t *testing.T) {
+	const capacity = 48
+	for _, title := range []string{
+		`Zero-Knowledge "Proofs" für Alle`,
+		"<b>R&D</b>\n\\ok\t",
+		"\x00\x1f  ‧ ∑😀",
+		"",
+	} {
+		data, err := json.Marshal(struct{ Title string }{title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := NewString(string(data), len(data)+1)
+		circuit := &paperCircuit{Content: paper{make(String, capacity)}, Expected: make(String, len(expected))}
+		assignment := &paperCircuit{Content: paper{NewString(title, capacity)}, Expected: expected}
+		if err := test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+	}
+
+	// invalid UTF-8, copied as is
+	for _, title := range []string{"\xff", "a\x80", "\xc3", "\xc0\xaf", "\xe0\x80\xaf", "\xed\xa0\x80", "\xf0\x80\x80\xaf", "\xf4\x90\x80\x80", "\xc3\xa9\xa9"} {
+		data := `{"Title":"` + title + `"}`
+		expected := rawString(data, len(data)+1)
+		circuit := &paperCircuit{Content: paper{make(String, 8)}, Expected: make(String, len(expected))}
+		assignment := &paperCircuit{Content: paper{rawString(title, 8)}, Expected: expected}
+		if err := test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()); err == nil {
+			t.Fatalf("expected %q to be rejected", title)
+		}
+	}
+}
+
+type member struct {
+	Email  OptionalString `json:"email,omitempty"`
+	Name   String         `json:"name"`
+	Age    Integer        `zkjson:"age_years,omitempty" json:"age"`
+	Active Bool           `json:",omitempty"`
+	Secret Integer        `json:"-"`
+}
+
+type memberJSON struct {
+	Email  *string `json:"email,omitempty"`
+	Name   string  `json:"name"`
+	Age    int64   `json:"age_years,omitempty"`
+	Active bool    `json:",omitempty"`
+}
+
+func makeMember(in memberJSON) member {
+	return member{
+		Email:  NewOptionalString(in.Email, 16),
+		Name:   NewString(in.Name, 8),
+		Age:    NewInteger(in.Age, 3),
+		Active: NewBool(in.Active),
+		Secret: NewInteger(42, 2),
+	}
+}
+
+type memberCircuit struct {
+	Content  member
+	Expected String `gnark:",public"`
+}
+
+func (c *memberCircuit) Define(api frontend.API) error {
+	assertEncoding(api, c.Content, c.Expected)
+	return nil
+}
+
+func TestEncodeTaggedKeys(t *testing.T) {
+	email := "bob@uni.edu"
+	for _, in := range []memberJSON{
+		{Email: &email, Name: "Bob", Age: 31, Active: true},
+		{Name: "Bob", Age: 31},
+		{Name: "Bob", Active: true},
+		{},
+	} {
+		data, err := json.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := NewString(string(data), len(data)+1)
+		circuit := &memberCircuit{Content: makeMember(memberJSON{}), Expected: make(String, len(expected))}
+		if err := test.IsSolved(circuit, &memberCircuit{Content: makeMember(in), Expected: expected}, ecc.BN254.ScalarField()); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+	}
+}
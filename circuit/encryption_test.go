@@ -1,6 +1,8 @@
 package circuit
 
 import (
+	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/consensys/gnark-crypto/ecc"
@@ -37,3 +39,115 @@ func Test_EncryptMIMCCircuit(t *testing.T) {
 		EncContent: enc,
 	}, test.WithCurves(ecc.BN254), test.WithBackends(backend.GROTH16))
 }
+
+type EncryptCommitCircuit struct {
+	Key          frontend.Variable
+	Content      frontend.Variable
+	EncContent   frontend.Variable `gnark:",public"`
+	CommittedKey frontend.Variable `gnark:",public"`
+	Blinding     frontend.Variable
+}
+
+func (circuit *EncryptCommitCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(circuit.EncContent, encryptMimc(api, circuit.Key, circuit.Content))
+	api.AssertIsEqual(circuit.CommittedKey, commit(api, circuit.Key, circuit.Blinding))
+	return nil
+}
+
+func TestEncryptMimcCurves(t *testing.T) {
+	key := big.NewInt(1111)
+	message := big.NewInt(2222)
+	bn254Enc := EncryptMimcFr(*new(fr.Element).SetBigInt(key), *new(fr.Element).SetBigInt(message))
+	if EncryptMimc(ecc.BN254, key, message).Cmp(bn254Enc.BigInt(new(big.Int))) != 0 {
+		t.Fatal("EncryptMimc differs from EncryptMimcFr on BN254")
+	}
+	for _, curve := range Curves() {
+		assignment := EncryptCommitCircuit{
+			Key:          key,
+			Content:      message,
+			EncContent:   EncryptMimc(curve, key, message),
+			CommittedKey: CommitKey(curve, key, big.NewInt(3333)),
+			Blinding:     big.NewInt(3333),
+		}
+		if err := test.IsSolved(&EncryptCommitCircuit{}, &assignment, curve.ScalarField()); err != nil {
+			t.Errorf("%s: %v", curve, err)
+		}
+		if CommitKey(curve, key, big.NewInt(3334)).Cmp(assignment.CommittedKey.(*big.Int)) == 0 {
+			t.Errorf("%s: the commitment does not depend on the blinding factor", curve)
+		}
+	}
+}
+
+func TestEncryptRecordNonce(t *testing.T) {
+	key := big.NewInt(1111)
+	// two equal blocks
+	record := []byte(strings.Repeat("a", 2*MergeLen))
+	first := EncryptRecord(ecc.BN254, record, key, big.NewInt(1))
+	second := EncryptRecord(ecc.BN254, record, key, big.NewInt(2))
+	if first[0].Cmp(first[1]) == 0 {
+		t.Fatal("equal blocks of a record have equal ciphertexts")
+	}
+	if first[0].Cmp(second[0]) == 0 {
+		t.Fatal("a record has the same ciphertext under two nonces")
+	}
+}
+
+type RecordTagCircuit struct {
+	Key    frontend.Variable
+	Record Record `gnark:",public"`
+}
+
+func (circuit *RecordTagCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(circuit.Record.Tag, recordTag(api, circuit.Key, circuit.Record))
+	return nil
+}
+
+func TestRecordTag(t *testing.T) {
+	key := big.NewInt(1111)
+	record := []byte(strings.Repeat("a", 2*MergeLen))
+	for _, curve := range Curves() {
+		enc, err := Encrypt(curve, record, key, big.NewInt(1), 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !enc.Verify(curve, key) {
+			t.Fatalf("%s: the tag does not verify", curve)
+		}
+		if err := test.IsSolved(&RecordTagCircuit{Record: EmptyRecord(4)}, &RecordTagCircuit{Key: key, Record: enc.Record()}, curve.ScalarField()); err != nil {
+			t.Errorf("%s: %v", curve, err)
+		}
+
+		enc.Blocks[0] = new(big.Int).Add(enc.Blocks[0], big.NewInt(1))
+		if enc.Verify(curve, key) {
+			t.Fatalf("%s: the tag verifies a tampered block", curve)
+		}
+		if err := test.IsSolved(&RecordTagCircuit{Record: EmptyRecord(4)}, &RecordTagCircuit{Key: key, Record: enc.Record()}, curve.ScalarField()); err == nil {
+			t.Errorf("%s: the circuit accepts a tampered block", curve)
+		}
+	}
+	if _, err := Encrypt(ecc.BN254, record, key, big.NewInt(1), 1); err == nil {
+		t.Fatal("expected the record not to fit")
+	}
+}
+
+func TestDecryptRecord(t *testing.T) {
+	key := big.NewInt(1111)
+	// one full block and a partial one
+	record := []byte(`{"Name":"Alice","Status":"Active","Year":3}`)
+	for _, curve := range Curves() {
+		enc, err := Encrypt(curve, record, key, big.NewInt(5), 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := enc.Decrypt(curve, key)
+		if err != nil {
+			t.Fatalf("%s: %v", curve, err)
+		}
+		if string(res) != string(record) {
+			t.Fatalf("%s: decrypted %q", curve, res)
+		}
+		if _, err := DecryptRecord(curve, enc.Blocks, big.NewInt(1112), enc.Nonce); err == nil {
+			t.Errorf("%s: decrypted under a wrong key", curve)
+		}
+	}
+}
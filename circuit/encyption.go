@@ -4,7 +4,10 @@ import (
 	"errors"
 	"math/big"
 
+	bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr/mimc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/mimc"
 	bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr/mimc"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/frontend"
@@ -12,9 +15,59 @@ import (
 
 const MergeLen = 31
 
+// NonceBits is the size of the nonce of a record. The key stream of block i is
+// Encrypt(key, nonce||i) of the primitive of the circuit, i taking the low counterBits bits.
+const (
+	NonceBits   = 128
+	counterBits = 32
+)
+
 var encryptFuncs map[ecc.ID]func(MiMC, frontend.Variable) frontend.Variable
 var newMimc map[ecc.ID]func(frontend.API) MiMC
 
+// mimcParams are the round constants and exponent of MiMC on the scalar field of each curve,
+// as in gnark-crypto
+var mimcParams = map[ecc.ID]struct {
+	constants func() []big.Int
+	exponent  int64
+}{
+	ecc.BN254:     {bn254.GetConstants, 5},
+	ecc.BLS12_381: {bls12381.GetConstants, 5},
+	ecc.BLS12_377: {bls12377.GetConstants, 17},
+	ecc.BW6_761:   {bw6761.GetConstants, 5},
+}
+
+func init() {
+	encryptFuncs = make(map[ecc.ID]func(MiMC, frontend.Variable) frontend.Variable)
+	newMimc = make(map[ecc.ID]func(frontend.API) MiMC)
+	for id, params := range mimcParams {
+		id, constants := id, params.constants
+		if params.exponent == 17 {
+			encryptFuncs[id] = encryptPow17
+		} else {
+			encryptFuncs[id] = encryptPow5
+		}
+		newMimc[id] = func(api frontend.API) MiMC {
+			return MiMC{params: constants(), id: id, k: 0, api: api}
+		}
+	}
+}
+
+// Curves lists the curves whose scalar field the circuits can be compiled on
+func Curves() []ecc.ID {
+	return []ecc.ID{ecc.BN254, ecc.BLS12_381, ecc.BLS12_377, ecc.BW6_761}
+}
+
+// curveOf returns the curve whose scalar field is field
+func curveOf(field *big.Int) (ecc.ID, error) {
+	for _, id := range Curves() {
+		if id.ScalarField().Cmp(field) == 0 {
+			return id, nil
+		}
+	}
+	return ecc.UNKNOWN, errors.New("unknown curve id")
+}
+
 // MiMC contains the params of the Mimc hash func and the curves on which it is implemented
 type MiMC struct {
 	params []big.Int           // c_i
@@ -24,9 +77,14 @@ type MiMC struct {
 	api    frontend.API        // underlying constraint system
 }
 
-// NewMiMC returns a MiMC instance, than can be used in a gnark circuit
+// NewMiMC returns a MiMC instance, than can be used in a gnark circuit. The curve is the one
+// whose scalar field the circuit is compiled on.
 func NewMiMC(api frontend.API) (MiMC, error) {
-	if constructor, ok := newMimc[ecc.BN254]; ok {
+	id, err := curveOf(api.Compiler().Field())
+	if err != nil {
+		return MiMC{}, err
+	}
+	if constructor, ok := newMimc[id]; ok {
 		return constructor(api), nil
 	}
 	return MiMC{}, errors.New("unknown curve id")
@@ -67,6 +125,14 @@ func pow5(api frontend.API, x frontend.Variable) frontend.Variable {
 	return api.Mul(r, x)
 }
 
+func pow17(api frontend.API, x frontend.Variable) frontend.Variable {
+	r := api.Mul(x, x)
+	r = api.Mul(r, r)
+	r = api.Mul(r, r)
+	r = api.Mul(r, r)
+	return api.Mul(r, x)
+}
+
 // m is the message, k the key
 func encryptPow5(h MiMC, m frontend.Variable) frontend.Variable {
 	x := m
@@ -76,37 +142,34 @@ func encryptPow5(h MiMC, m frontend.Variable) frontend.Variable {
 	return h.api.Add(x, h.k)
 }
 
-func newMimcBN254(api frontend.API) MiMC {
-	res := MiMC{}
-	res.params = bn254.GetConstants()
-	res.id = ecc.BN254
-	res.k = 0
-	res.api = api
-	return res
+func encryptPow17(h MiMC, m frontend.Variable) frontend.Variable {
+	x := m
+	for i := 0; i < len(h.params); i++ {
+		x = pow17(h.api, h.api.Add(x, h.k, h.params[i]))
+	}
+	return h.api.Add(x, h.k)
 }
 
 func encryptMimc(api frontend.API, key frontend.Variable, message frontend.Variable) frontend.Variable {
-	// create Mimc function with the api
-	F, _ := NewMiMC(api)
-	// set the fields
-	F.params = bn254.GetConstants()
-	F.id = ecc.BN254
+	F, err := NewMiMC(api)
+	if err != nil {
+		panic(err)
+	}
 	F.k = key
-	F.api = api
-	// encrypt the message using CBC mode
-	// Define an initialization vector (IV). This can be a predefined constant or a random value.
-	// We'll use a predefined constant for this example.
-	return encryptPow5(F, message)
+	return encryptFuncs[F.id](F, message)
 }
 
-// Message[0] is the length of the whole message
-func encrypt(api frontend.API, key frontend.Variable, message []frontend.Variable) []frontend.Variable {
+// Message[0] is the length of the whole message. Each block is added to the key stream of the
+// nonce (counter mode), so equal blocks or records encrypted under different nonces do not
+// give equal ciphertexts.
+func encrypt(api frontend.API, key frontend.Variable, nonce frontend.Variable, message []frontend.Variable) []frontend.Variable {
+	// the nonce must not overflow into the counter of another nonce
+	api.ToBinary(nonce, NonceBits)
 	merged, isDummy := compress(api, message)
 	res := make([]frontend.Variable, len(merged))
 	for i := 0; i < len(merged); i++ {
-		//api.Println(merged[i])
-		res[i] = api.Select(isDummy[i], 0, encryptMimc(api, key, merged[i]))
-		//api.Println(res[i])
+		counter := api.Add(api.Mul(nonce, leftShift(1, counterBits)), i)
+		res[i] = api.Select(isDummy[i], 0, api.Add(merged[i], encryptBlock(api, key, counter)))
 	}
 	return res
 }
@@ -132,3 +195,39 @@ func compress(api frontend.API, msg []frontend.Variable) ([]frontend.Variable, [
 	}
 	return res, isDummy
 }
+
+// Record is an encrypted record as published: its blocks padded with zeros to the capacity of
+// the circuit, the nonce of counter mode and the MAC tag over both
+type Record struct {
+	Blocks []frontend.Variable
+	Nonce  frontend.Variable
+	Tag    frontend.Variable
+}
+
+func EmptyRecord(capacity int) Record {
+	res := Record{Blocks: make([]frontend.Variable, capacity), Nonce: 0, Tag: 0}
+	for i := range res.Blocks {
+		res.Blocks[i] = 0
+	}
+	return res
+}
+
+// macDomain derives the MAC key from the encryption key, key stream counters stay below it
+var macDomain = leftShift(1, NonceBits+counterBits)
+
+// recordDigest is the hash H(nonce, blocks...) of the public part of a record
+func recordDigest(api frontend.API, record Record) frontend.Variable {
+	return hashItems(api, append([]frontend.Variable{record.Nonce}, record.Blocks...))
+}
+
+// recordTag is the encrypt-then-MAC tag E(k', recordDigest) with k' = E(key, macDomain)
+func recordTag(api frontend.API, key frontend.Variable, record Record) frontend.Variable {
+	macKey := encryptBlock(api, key, macDomain)
+	return encryptBlock(api, macKey, recordDigest(api, record))
+}
+
+// checkRecord asserts that record is the encryption of message under key, tag included
+func checkRecord(api frontend.API, key frontend.Variable, record Record, message []frontend.Variable) {
+	assertArrayEqualWithUnequalLength(api, record.Blocks, encrypt(api, key, record.Nonce, message))
+	api.AssertIsEqual(record.Tag, recordTag(api, key, record))
+}
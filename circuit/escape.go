@@ -0,0 +1,151 @@
+package circuit
+
+import (
+	"encoding/json"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// shortEscapes are the characters encoding/json escapes with a backslash and a letter
+var shortEscapes = []struct {
+	char   int
+	letter int
+}{{'"', '"'}, {'\\', '\\'}, {'\b', 'b'}, {'\f', 'f'}, {'\n', 'n'}, {'\r', 'r'}, {'\t', 't'}}
+
+// htmlEscapes are the characters encoding/json escapes as \u00XX besides the other control
+// characters
+var htmlEscapes = []int{'<', '>', '&'}
+
+// escapeString returns the characters of the JSON string str, without its quotes, as
+// encoding/json writes them: one part of at most 6 bytes per byte of str. U+2028 and U+2029,
+// E2 80 A8 and E2 80 A9, are written \u2028 and \u2029 in the part of their first byte. With
+// WithCanonicalJSON, only the quote, the backslash and the control characters are escaped, as
+// escapeCanonical escapes them.
+func escapeString(api frontend.API, str String) [][]frontend.Variable {
+	canonical := isCanonical(api)
+	n := len(str) - 1
+	b := make([]frontend.Variable, n)
+	dummy := make([]frontend.Variable, n)
+	bits := make([][]frontend.Variable, n)
+	for i := 0; i < n; i++ {
+		dummy[i] = isDummy(api, str[i+1])
+		b[i] = api.Select(dummy[i], 0, str[i+1])
+		bits[i] = api.ToBinary(b[i], 8)
+	}
+	checkUTF8(api, b, bits)
+
+	isLine := make([]frontend.Variable, n)
+	for i := 0; i < n; i++ {
+		isLine[i] = frontend.Variable(0)
+		if !canonical && i+2 < n {
+			third := api.Add(isEqual(api, b[i+2], 0xA8), isEqual(api, b[i+2], 0xA9))
+			isLine[i] = and(api, isEqual(api, b[i], 0xE2), and(api, isEqual(api, b[i+1], 0x80), third))
+		}
+	}
+	var res [][]frontend.Variable
+	for i := 0; i < n; i++ {
+		isShort, isControlShort, letter := frontend.Variable(0), frontend.Variable(0), frontend.Variable(0)
+		for _, e := range shortEscapes {
+			is := isEqual(api, b[i], e.char)
+			isShort = api.Add(isShort, is)
+			if e.char < 0x20 {
+				isControlShort = api.Add(isControlShort, is)
+			}
+			letter = api.Add(letter, api.Mul(is, e.letter))
+		}
+		// the control characters are below 0x20
+		isControl := api.IsZero(api.Add(bits[i][5], bits[i][6], bits[i][7]))
+		isHex := api.Add(api.Sub(isControl, isControlShort), isLine[i])
+		if !canonical {
+			for _, c := range htmlEscapes {
+				isHex = api.Add(isHex, isEqual(api, b[i], c))
+			}
+		}
+		lineDigit := frontend.Variable('8')
+		if i+2 < n {
+			lineDigit = api.Add(api.Sub(b[i+2], 0xA8), '8')
+		}
+
+		part := make([]frontend.Variable, 7)
+		part[0] = api.Add(1, isShort, api.Mul(isHex, 5))
+		part[1] = api.Select(api.Add(isShort, isHex), '\\', b[i])
+		part[2] = api.Select(isHex, 'u', api.Select(isShort, letter, DUMMY))
+		part[3] = api.Select(isHex, api.Select(isLine[i], '2', '0'), DUMMY)
+		part[4] = api.Select(isHex, '0', DUMMY)
+		part[5] = api.Select(isHex, api.Select(isLine[i], '2', hexDigit(api, bits[i][4:])), DUMMY)
+		part[6] = api.Select(isHex, api.Select(isLine[i], lineDigit, hexDigit(api, bits[i][:4])), DUMMY)
+
+		// the bytes following E2 are written with it
+		hidden := dummy[i]
+		for j := 1; j <= 2 && i-j >= 0; j++ {
+			hidden = api.Add(hidden, isLine[i-j])
+		}
+		res = append(res, hideEncoding(api, hidden, [][]frontend.Variable{part})...)
+	}
+	return res
+}
+
+// hexDigit returns the lowercase hexadecimal digit of the 4 bits, least significant first
+func hexDigit(api frontend.API, bits []frontend.Variable) frontend.Variable {
+	v := api.FromBinary(bits...)
+	isLetter := and(api, bits[3], api.Or(bits[2], bits[1]))
+	return api.Add(v, '0', api.Mul(isLetter, 'a'-'0'-10))
+}
+
+// checkUTF8 asserts that the bytes b are valid UTF-8, bits being their binary decomposition:
+// no unexpected continuation byte, no overlong encoding, no surrogate and nothing above U+10FFFF
+func checkUTF8(api frontend.API, b []frontend.Variable, bits [][]frontend.Variable) {
+	pending := frontend.Variable(0) // continuation bytes still expected
+	for i := range b {
+		bit := bits[i]
+		isCont := and(api, bit[7], boolNeg(api, bit[6]))
+		api.AssertIsEqual(isCont, boolNeg(api, api.IsZero(pending)))
+		lead := and(api, bit[7], bit[6])
+		lead2 := and(api, lead, boolNeg(api, bit[5]))
+		lead3 := and(api, lead, and(api, bit[5], boolNeg(api, bit[4])))
+		lead4 := and(api, lead, and(api, bit[5], and(api, bit[4], boolNeg(api, bit[3]))))
+		// F8-FF
+		api.AssertIsEqual(and(api, lead, and(api, bit[5], and(api, bit[4], bit[3]))), 0)
+		// C0 and C1 are overlong, F5-F7 above U+10FFFF
+		api.AssertIsEqual(and(api, lead2, api.IsZero(api.Add(bit[4], bit[3], bit[2], bit[1]))), 0)
+		api.AssertIsEqual(and(api, lead4, and(api, bit[2], api.Or(bit[1], bit[0]))), 0)
+		if i+1 < len(b) {
+			// E0 80-9F is overlong, ED A0-BF a surrogate, F0 80-8F overlong, F4 90-BF above U+10FFFF
+			next := bits[i+1]
+			high := api.Or(next[5], next[4])
+			api.AssertIsEqual(and(api, isEqual(api, b[i], 0xE0), boolNeg(api, next[5])), 0)
+			api.AssertIsEqual(and(api, isEqual(api, b[i], 0xED), next[5]), 0)
+			api.AssertIsEqual(and(api, isEqual(api, b[i], 0xF0), boolNeg(api, high)), 0)
+			api.AssertIsEqual(and(api, isEqual(api, b[i], 0xF4), high), 0)
+		}
+		pending = api.Select(isCont, api.Sub(pending, 1), api.Add(lead2, api.Mul(lead3, 2), api.Mul(lead4, 3)))
+	}
+	api.AssertIsEqual(pending, 0)
+}
+
+// constantString returns the value of str if it is known when the circuit is compiled, e.g.
+// a key of a dict
+func constantString(api frontend.API, str String) (string, bool) {
+	length, ok := api.Compiler().ConstantValue(str[0])
+	if !ok || !length.IsInt64() || length.Int64() > int64(len(str)-1) {
+		return "", false
+	}
+	res := make([]byte, length.Int64())
+	for i := range res {
+		c, ok := api.Compiler().ConstantValue(str[i+1])
+		if !ok || !c.IsInt64() || c.Int64() < 0 || c.Int64() > 255 {
+			return "", false
+		}
+		res[i] = byte(c.Int64())
+	}
+	return string(res), true
+}
+
+// escapeJSON is the native counterpart of escapeString
+func escapeJSON(s string) []byte {
+	res, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	return res[1 : len(res)-1]
+}
@@ -0,0 +1,5 @@
+package circuit
+
+// CompareContent lets the tests of package circuit_test run rules against their native
+// counterparts of package policy, which imports circuit
+var CompareContent = compareContent
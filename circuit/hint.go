@@ -11,6 +11,18 @@ func init() {
 	hint.Register(NBits)
 	hint.Register(mergeHint)
 	hint.Register(batchMergeHint)
+	hint.Register(getSign)
+}
+
+// getSign returns 1 if the input is a negative number, above half the field
+func getSign(field *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	half := new(big.Int).Rsh(field, 1)
+	if x := new(big.Int).Mod(inputs[0], field); x.Cmp(half) > 0 {
+		outputs[0].SetInt64(1)
+	} else {
+		outputs[0].SetInt64(0)
+	}
+	return nil
 }
 func getDecimal(field *big.Int, inputs []*big.Int, outputs []*big.Int) error {
 	maxDigit := inputs[0].Int64()
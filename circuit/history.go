@@ -0,0 +1,52 @@
+package circuit
+
+import (
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// History is the public state of the lineage of a credential at one of its versions. Digest
+// chains the digests of all its records so far: H(0, d_0) for the issued record at version 0,
+// then H(Digest_{v-1}, d_v) for the record of version v. Issued is d_0, the digest the issuer
+// signed, carried along the lineage so that every edit checks the signature.
+type History struct {
+	Version frontend.Variable
+	Digest  frontend.Variable
+	Issued  frontend.Variable
+}
+
+// CheckHistory asserts that newHistory is the version following oldHistory, extended with
+// newRecord. prevDigest is the private digest of the history before oldRecord, which opens
+// oldHistory to oldRecord so that the edit cannot start from a record outside the lineage.
+func CheckHistory(api frontend.API, oldHistory History, newHistory History, prevDigest frontend.Variable, oldRecord Record, newRecord Record) {
+	oldDigest := recordDigest(api, oldRecord)
+	// the lineage starts from 0 at version 0, with the issued record
+	issued := api.IsZero(oldHistory.Version)
+	api.AssertIsEqual(api.Mul(issued, prevDigest), 0)
+	api.AssertIsEqual(api.Mul(issued, api.Sub(oldHistory.Issued, oldDigest)), 0)
+	api.AssertIsEqual(oldHistory.Digest, hashItems(api, []frontend.Variable{prevDigest, oldDigest}))
+	api.AssertIsEqual(newHistory.Version, api.Add(oldHistory.Version, 1))
+	api.AssertIsEqual(newHistory.Digest, chainHistory(api, oldHistory.Digest, newRecord))
+	api.AssertIsEqual(newHistory.Issued, oldHistory.Issued)
+}
+
+func chainHistory(api frontend.API, digest frontend.Variable, record Record) frontend.Variable {
+	return hashItems(api, []frontend.Variable{digest, recordDigest(api, record)})
+}
+
+func EmptyHistory() History {
+	return History{Version: 0, Digest: 0, Issued: 0}
+}
+
+// issuedDigest is the digest of the issued record the issuer signed: the one of oldRecord at
+// version 0, the one history carries past it
+func issuedDigest(api frontend.API, history History, oldRecord Record) frontend.Variable {
+	return api.Select(api.IsZero(history.Version), recordDigest(api, oldRecord), history.Issued)
+}
+
+// ChainHistory is the native counterpart of the history digest extending digest with ct, 0
+// starting the lineage
+func (c Cipher) ChainHistory(digest *big.Int, ct *Ciphertext) *big.Int {
+	return c.Primitive.HashOn(c.Curve, digest, c.RecordDigest(ct.Nonce, ct.Blocks))
+}
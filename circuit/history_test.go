@@ -0,0 +1,84 @@
+package circuit
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+type historyCircuit struct {
+	OldRecord  Record  `gnark:",public"`
+	NewRecord  Record  `gnark:",public"`
+	OldHistory History `gnark:",public"`
+	NewHistory History `gnark:",public"`
+	PrevDigest frontend.Variable
+}
+
+func (c *historyCircuit) Define(api frontend.API) error {
+	CheckHistory(api, c.OldHistory, c.NewHistory, c.PrevDigest, c.OldRecord, c.NewRecord)
+	return nil
+}
+
+func TestCheckHistory(t *testing.T) {
+	key := new(fr.Element).SetUint64(42)
+	cipher := Cipher{ecc.BN254, MimcPrimitive}
+	records := []*Ciphertext{
+		makeTestRecord(cipher, `{"Status":"Approved"}`, key, 1, 2),
+		makeTestRecord(cipher, `{"Status":"Ongoing"}`, key, 2, 2),
+		makeTestRecord(cipher, `{"Status":"Graduated"}`, key, 3, 2),
+	}
+	digests := []*big.Int{big.NewInt(0)}
+	for _, r := range records {
+		digests = append(digests, cipher.ChainHistory(digests[len(digests)-1], r))
+	}
+	issued := cipher.RecordDigest(records[0].Nonce, records[0].Blocks)
+	// the edit from version v to v+1
+	assignment := func(v int) *historyCircuit {
+		return &historyCircuit{
+			OldRecord:  records[v].Record(),
+			NewRecord:  records[v+1].Record(),
+			OldHistory: History{Version: v, Digest: digests[v+1], Issued: issued},
+			NewHistory: History{Version: v + 1, Digest: digests[v+2], Issued: issued},
+			PrevDigest: digests[v],
+		}
+	}
+	circuit := &historyCircuit{OldRecord: EmptyRecord(2), NewRecord: EmptyRecord(2), OldHistory: EmptyHistory(), NewHistory: EmptyHistory()}
+	for v := 0; v < 2; v++ {
+		if err := test.IsSolved(circuit, assignment(v), ecc.BN254.ScalarField()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replay := assignment(0)
+	replay.OldHistory, replay.NewHistory = History{Version: 1, Digest: digests[2], Issued: issued}, History{Version: 2, Digest: cipher.ChainHistory(digests[2], records[1]), Issued: issued}
+	if err := test.IsSolved(circuit, replay, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected an edit from a record outside the lineage to be rejected")
+	}
+	skipped := assignment(1)
+	skipped.NewHistory.Version = 3
+	if err := test.IsSolved(circuit, skipped, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected a version to be skipped to be rejected")
+	}
+	restarted := assignment(1)
+	restarted.OldHistory.Version = 0
+	restarted.NewHistory.Version = 1
+	if err := test.IsSolved(circuit, restarted, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected a lineage restarting from a later record to be rejected")
+	}
+	// the issued digest is the one of the record at version 0, and it is carried unchanged
+	reissued := assignment(0)
+	reissued.OldHistory.Issued = cipher.RecordDigest(records[1].Nonce, records[1].Blocks)
+	reissued.NewHistory.Issued = reissued.OldHistory.Issued
+	if err := test.IsSolved(circuit, reissued, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected an issued digest of another record to be rejected")
+	}
+	swapped := assignment(1)
+	swapped.NewHistory.Issued = cipher.RecordDigest(records[1].Nonce, records[1].Blocks)
+	if err := test.IsSolved(circuit, swapped, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected the issued digest to be carried to the new history")
+	}
+}
@@ -0,0 +1,179 @@
+package circuit
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+)
+
+// MerkleProof is the private path from the leaf of a String to the root of a Merkle set
+type MerkleProof struct {
+	Siblings   []frontend.Variable
+	Directions []frontend.Variable // 1 if the node is the right child of its parent
+}
+
+func NewMerkleProof(depth int) MerkleProof {
+	res := MerkleProof{
+		Siblings:   make([]frontend.Variable, depth),
+		Directions: make([]frontend.Variable, depth),
+	}
+	for i := 0; i < depth; i++ {
+		res.Siblings[i] = 0
+		res.Directions[i] = 0
+	}
+	return res
+}
+
+// checkMerkleSet returns 1 if value is a leaf of the Merkle tree of the given root. Unlike
+// checkOneOfSet, the set only appears through its root, so it can grow up to 2^depth items
+// without changing the circuit.
+func checkMerkleSet(api frontend.API, root frontend.Variable, proof MerkleProof, value String) frontend.Variable {
+	if len(proof.Siblings) != len(proof.Directions) {
+		panic("Invalid Merkle proof")
+	}
+	node := stringLeaf(api, value)
+	for i := 0; i < len(proof.Siblings); i++ {
+		api.AssertIsBoolean(proof.Directions[i])
+		left := api.Select(proof.Directions[i], proof.Siblings[i], node)
+		right := api.Select(proof.Directions[i], node, proof.Siblings[i])
+		node = hashItems(api, []frontend.Variable{left, right})
+	}
+	return isEqual(api, node, root)
+}
+
+// stringLeaf hashes the length and characters of a String, packed 31 per field element
+func stringLeaf(api frontend.API, value String) frontend.Variable {
+	items := make([]frontend.Variable, len(value))
+	copy(items, value)
+	return hashItems(api, mergeItems(api, items, 8))
+}
+
+type withMerkleProofs struct {
+	limit  Limit
+	proofs map[string]MerkleProof
+}
+
+// WithMerkleProofs attaches the private Merkle proofs of the RuleMerkleSet rules of limit, keyed
+// by field path. The proofs can not be part of the limit, which is public.
+func WithMerkleProofs(limit Limit, proofs map[string]MerkleProof) Limit {
+	return withMerkleProofs{limit: limit, proofs: proofs}
+}
+
+func (l withMerkleProofs) Rules(api frontend.API) []Rule {
+	rules := l.limit.Rules(api)
+	for i := range rules {
+		if rules[i].Kind != RuleMerkleSet {
+			continue
+		}
+		proof, ok := l.proofs[rules[i].Path]
+		if !ok {
+			panic("Missing Merkle proof of " + rules[i].Path)
+		}
+		rules[i].MerkleProof = proof
+	}
+	return rules
+}
+
+// MerkleSet is the native Merkle tree of a set of strings, whose root is the public input of
+// RuleMerkleSet. Missing leaves are zero.
+type MerkleSet struct {
+	primitive Primitive
+	capacity  int
+	index     map[string]int
+	levels    [][]fr.Element // levels[0] are the leaves, levels[depth][0] is the root
+}
+
+// NewMerkleSet builds the tree of depth levels over set, for a field held in Strings of the
+// given capacity
+func NewMerkleSet(set []string, capacity int, depth int) (*MerkleSet, error) {
+	return NewMerkleSetWith(MimcPrimitive, set, capacity, depth)
+}
+
+// NewMerkleSetWith is NewMerkleSet for a circuit hashing with p
+func NewMerkleSetWith(p Primitive, set []string, capacity int, depth int) (*MerkleSet, error) {
+	if len(set) > 1<<depth {
+		return nil, errors.New("the set does not fit in the tree")
+	}
+	res := &MerkleSet{primitive: p, capacity: capacity, index: map[string]int{}}
+	leaves := make([]fr.Element, 1<<depth)
+	for i, s := range set {
+		if _, ok := res.index[s]; ok {
+			return nil, errors.New("duplicated item " + s)
+		}
+		leaf, err := stringLeafFr(p, s, capacity)
+		if err != nil {
+			return nil, err
+		}
+		res.index[s] = i
+		leaves[i] = leaf
+	}
+	res.levels = append(res.levels, leaves)
+	for len(leaves) > 1 {
+		parents := make([]fr.Element, len(leaves)/2)
+		for i := range parents {
+			parents[i] = hashFr(p, leaves[2*i], leaves[2*i+1])
+		}
+		res.levels = append(res.levels, parents)
+		leaves = parents
+	}
+	return res, nil
+}
+
+func (m *MerkleSet) Root() fr.Element {
+	return m.levels[len(m.levels)-1][0]
+}
+
+// Proof returns the witness of RuleMerkleSet for s
+func (m *MerkleSet) Proof(s string) (MerkleProof, error) {
+	i, ok := m.index[s]
+	if !ok {
+		return MerkleProof{}, errors.New(s + " is not in the set")
+	}
+	depth := len(m.levels) - 1
+	res := NewMerkleProof(depth)
+	for level := 0; level < depth; level++ {
+		res.Siblings[level] = m.levels[level][i^1].BigInt(new(big.Int))
+		res.Directions[level] = i & 1
+		i >>= 1
+	}
+	return res, nil
+}
+
+// StringLeaf is the native counterpart of stringLeaf for a String of the given capacity
+func StringLeaf(s string, capacity int) (fr.Element, error) {
+	return stringLeafFr(MimcPrimitive, s, capacity)
+}
+
+func stringLeafFr(p Primitive, s string, capacity int) (fr.Element, error) {
+	ascii := StringToAscii(s)
+	if len(ascii)+1 > capacity {
+		return fr.Element{}, errors.New("Invalid Capacity")
+	}
+	items := make([]int64, capacity)
+	items[0] = int64(len(ascii))
+	copy(items[1:], ascii)
+	const rate = 253 / 8
+	var chunks []fr.Element
+	for i := 0; i < len(items); i += rate {
+		chunk := new(big.Int)
+		for j := 0; j < rate && i+j < len(items); j++ {
+			chunk.Add(chunk, new(big.Int).Lsh(big.NewInt(items[i+j]), uint(8*j)))
+		}
+		chunks = append(chunks, *new(fr.Element).SetBigInt(chunk))
+	}
+	return hashFr(p, chunks...), nil
+}
+
+// hashFr is the native counterpart of hashItems on BN254
+func hashFr(p Primitive, inputs ...fr.Element) fr.Element {
+	items := make([]*big.Int, len(inputs))
+	for i := range inputs {
+		items[i] = inputs[i].BigInt(new(big.Int))
+	}
+	var res fr.Element
+	res.SetBigInt(p.HashOn(ecc.BN254, items...))
+	return res
+}
@@ -0,0 +1,68 @@
+package circuit
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+const (
+	testSetCapacity = 40 // more than one 31-byte chunk per leaf
+	testSetDepth    = 10
+)
+
+type MerkleSetCircuit struct {
+	Root  frontend.Variable `gnark:",public"`
+	Value String
+	Proof MerkleProof
+}
+
+func (c *MerkleSetCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(checkMerkleSet(api, c.Root, c.Proof, c.Value), 1)
+	return nil
+}
+
+func TestMerkleSet(t *testing.T) {
+	var set []string
+	for i := 0; i < 1000; i++ {
+		set = append(set, fmt.Sprintf("COURSE-%04d", i))
+	}
+	tree, err := NewMerkleSet(set, testSetCapacity, testSetDepth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := tree.Root()
+	proof, err := tree.Proof("COURSE-0777")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Proof("COURSE-1000"); err == nil {
+		t.Fatal("proof of an item outside the set")
+	}
+
+	circuit := MerkleSetCircuit{
+		Value: EmptyString(testSetCapacity),
+		Proof: NewMerkleProof(testSetDepth),
+	}
+	assignment := MerkleSetCircuit{
+		Root:  root.BigInt(new(big.Int)),
+		Value: makeTestString("COURSE-0777", testSetCapacity),
+		Proof: proof,
+	}
+	if err := test.IsSolved(&circuit, &assignment, ecc.BN254.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+
+	assignment.Value = makeTestString("COURSE-0778", testSetCapacity)
+	if err := test.IsSolved(&circuit, &assignment, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("a value outside the path should not be accepted")
+	}
+
+	if _, err := NewMerkleSet(set, testSetCapacity, 9); err == nil {
+		t.Fatal("1000 items should not fit in a tree of depth 9")
+	}
+}
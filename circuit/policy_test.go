@@ -0,0 +1,62 @@
+package circuit_test
+
+import (
+	"testing"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/Nullus-Labs/IDEA-DAC/policy"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+type statusRecord struct {
+	Status circuit.String
+}
+
+// OneOfSetCircuit asserts that the edit from Old to New satisfies a oneOfSet rule over Set
+type OneOfSetCircuit struct {
+	Old, New statusRecord
+	Set      [3]circuit.String `gnark:",public"`
+}
+
+func (c *OneOfSetCircuit) Define(api frontend.API) error {
+	circuit.CompareContent(api, c.Old, c.New, []circuit.Rule{{Path: "Status", Kind: circuit.RuleOneOfSet, Set: c.Set[:]}})
+	return nil
+}
+
+// TestOneOfSetVerdicts checks that policy gives the verdicts of the circuit on the same sets and
+// values, an empty item matching nothing and a duplicated one matching once
+func TestOneOfSetVerdicts(t *testing.T) {
+	const capacity = 6
+	c := &OneOfSetCircuit{
+		Old: statusRecord{circuit.EmptyString(capacity)},
+		New: statusRecord{circuit.EmptyString(capacity)},
+		Set: [3]circuit.String{circuit.EmptyString(capacity), circuit.EmptyString(capacity), circuit.EmptyString(capacity)},
+	}
+	for _, tc := range []struct {
+		set   [3]string
+		value string
+		valid bool
+	}{
+		{[3]string{"A", "B", "C"}, "B", true},
+		{[3]string{"A", "B", "C"}, "D", false},
+		{[3]string{"A", "A", ""}, "A", true},
+		{[3]string{"A", "A", ""}, "B", false},
+		{[3]string{"A", "", ""}, "", false},
+		{[3]string{"", "", ""}, "", false},
+		{[3]string{"", "", ""}, "A", false},
+	} {
+		var set [3]circuit.String
+		for i, item := range tc.set {
+			set[i] = circuit.NewString(item, capacity)
+		}
+		value := statusRecord{circuit.NewString(tc.value, capacity)}
+		inCircuit := test.IsSolved(c, &OneOfSetCircuit{Old: value, New: value, Set: set}, ecc.BN254.ScalarField()) == nil
+		record := map[string]interface{}{"Status": tc.value}
+		native := len(policy.Check([]policy.Rule{{Path: "Status", Kind: policy.OneOfSet, Set: tc.set[:]}}, record, record)) == 0
+		if inCircuit != tc.valid || native != tc.valid {
+			t.Errorf("%q in %q: expected valid %v, got %v in the circuit and %v natively", tc.value, tc.set, tc.valid, inCircuit, native)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package circuit
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+)
+
+// Poseidon with the parameters of circomlib on the scalar field of BN254: x^5 S-box, 8 full
+// rounds and the partial rounds below for a state of t = inputs+1 elements, the capacity
+// element starting at 0. The round constants and the MDS matrix are generated with the Grain
+// LFSR of the reference implementation, as circomlib did.
+const (
+	poseidonFullRounds = 8
+	poseidonMaxInputs  = 16
+)
+
+// poseidonPartialRounds[t-2] is the number of partial rounds for a state of t elements
+var poseidonPartialRounds = [poseidonMaxInputs]int{56, 57, 56, 60, 60, 63, 64, 63, 60, 66, 60, 65, 70, 60, 64, 68}
+
+type poseidonParams struct {
+	constants []fr.Element   // constants[round*t+i]
+	mds       [][]fr.Element // mds[i][j] = 1/(x_i+y_j)
+}
+
+var poseidonCache struct {
+	sync.Mutex
+	params map[int]*poseidonParams
+}
+
+// getPoseidonParams returns the parameters for a state of t elements, generated on first use
+func getPoseidonParams(t int) *poseidonParams {
+	if t < 2 || t > poseidonMaxInputs+1 {
+		panic("Poseidon takes 1 to 16 inputs")
+	}
+	poseidonCache.Lock()
+	defer poseidonCache.Unlock()
+	if poseidonCache.params == nil {
+		poseidonCache.params = map[int]*poseidonParams{}
+	}
+	if res, ok := poseidonCache.params[t]; ok {
+		return res
+	}
+	res := newPoseidonParams(t, poseidonFullRounds, poseidonPartialRounds[t-2])
+	poseidonCache.params[t] = res
+	return res
+}
+
+func newPoseidonParams(t int, fullRounds int, partialRounds int) *poseidonParams {
+	const n = 254
+	g := newGrain(1, 0, n, t, fullRounds, partialRounds)
+	modulus := fr.Modulus()
+	res := &poseidonParams{constants: make([]fr.Element, (fullRounds+partialRounds)*t)}
+	for i := range res.constants {
+		c := g.randomInt(n)
+		for c.Cmp(modulus) >= 0 {
+			c = g.randomInt(n)
+		}
+		res.constants[i].SetBigInt(c)
+	}
+	for {
+		xy := make([]fr.Element, 2*t)
+		for distinct := false; !distinct; {
+			seen := map[fr.Element]bool{}
+			distinct = true
+			for i := range xy {
+				xy[i].SetBigInt(g.randomInt(n))
+				distinct = distinct && !seen[xy[i]]
+				seen[xy[i]] = true
+			}
+		}
+		res.mds = make([][]fr.Element, t)
+		ok := true
+		for i := 0; i < t && ok; i++ {
+			res.mds[i] = make([]fr.Element, t)
+			for j := 0; j < t && ok; j++ {
+				var sum fr.Element
+				sum.Add(&xy[i], &xy[t+j])
+				ok = !sum.IsZero()
+				res.mds[i][j].Inverse(&sum)
+			}
+		}
+		if ok {
+			return res
+		}
+	}
+}
+
+// grain is the self-shrinking Grain LFSR of the Poseidon reference implementation
+type grain struct {
+	state [80]bool
+}
+
+func newGrain(field int, sbox int, n int, t int, fullRounds int, partialRounds int) *grain {
+	g := &grain{}
+	pos := 0
+	push := func(x int, bits int) {
+		for i := bits - 1; i >= 0; i-- {
+			g.state[pos] = x>>i&1 == 1
+			pos++
+		}
+	}
+	push(field, 2)
+	push(sbox, 4)
+	push(n, 12)
+	push(t, 12)
+	push(fullRounds, 10)
+	push(partialRounds, 10)
+	push(1<<30-1, 30)
+	for i := 0; i < 160; i++ {
+		g.next()
+	}
+	return g
+}
+
+func (g *grain) next() bool {
+	s := &g.state
+	bit := s[62] != s[51] != s[38] != s[23] != s[13] != s[0]
+	copy(s[:], s[1:])
+	s[79] = bit
+	return bit
+}
+
+// randomBit keeps the second bit of each pair whose first bit is 1
+func (g *grain) randomBit() bool {
+	for !g.next() {
+		g.next()
+	}
+	return g.next()
+}
+
+// randomInt reads bits big endian
+func (g *grain) randomInt(bits int) *big.Int {
+	res := new(big.Int)
+	for i := 0; i < bits; i++ {
+		res.Lsh(res, 1)
+		if g.randomBit() {
+			res.SetBit(res, 0, 1)
+		}
+	}
+	return res
+}
+
+// poseidonFr is circomlib's poseidon(inputs) computed natively
+func poseidonFr(inputs ...fr.Element) fr.Element {
+	t := len(inputs) + 1
+	p := getPoseidonParams(t)
+	partialRounds := poseidonPartialRounds[t-2]
+	state := make([]fr.Element, t)
+	copy(state[1:], inputs)
+	next := make([]fr.Element, t)
+	for r := 0; r < poseidonFullRounds+partialRounds; r++ {
+		full := r < poseidonFullRounds/2 || r >= poseidonFullRounds/2+partialRounds
+		for i := range state {
+			state[i].Add(&state[i], &p.constants[r*t+i])
+			if full || i == 0 {
+				var x fr.Element
+				x.Square(&state[i]).Square(&x)
+				state[i].Mul(&state[i], &x)
+			}
+		}
+		for i := range next {
+			next[i].SetZero()
+			for j := range state {
+				var x fr.Element
+				x.Mul(&p.mds[i][j], &state[j])
+				next[i].Add(&next[i], &x)
+			}
+		}
+		state, next = next, state
+	}
+	return state[0]
+}
+
+// poseidon is the circuit counterpart of poseidonFr
+func poseidon(api frontend.API, inputs []frontend.Variable) frontend.Variable {
+	if curve, err := curveOf(api.Compiler().Field()); err != nil || curve != ecc.BN254 {
+		panic("Poseidon is only defined on the scalar field of BN254")
+	}
+	t := len(inputs) + 1
+	p := getPoseidonParams(t)
+	partialRounds := poseidonPartialRounds[t-2]
+	state := make([]frontend.Variable, t)
+	state[0] = 0
+	copy(state[1:], inputs)
+	for r := 0; r < poseidonFullRounds+partialRounds; r++ {
+		full := r < poseidonFullRounds/2 || r >= poseidonFullRounds/2+partialRounds
+		for i := range state {
+			state[i] = api.Add(state[i], p.constants[r*t+i].BigInt(new(big.Int)))
+			if full || i == 0 {
+				x2 := api.Mul(state[i], state[i])
+				state[i] = api.Mul(state[i], api.Mul(x2, x2))
+			}
+		}
+		next := make([]frontend.Variable, t)
+		for i := range next {
+			next[i] = 0
+			for j := range state {
+				next[i] = api.Add(next[i], api.Mul(p.mds[i][j].BigInt(new(big.Int)), state[j]))
+			}
+		}
+		state = next
+	}
+	return state[0]
+}
@@ -0,0 +1,49 @@
+package circuit
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+type PoseidonCircuit struct {
+	Inputs []frontend.Variable
+	Hash   frontend.Variable `gnark:",public"`
+}
+
+func (circuit *PoseidonCircuit) Define(api frontend.API) error {
+	api = WithPrimitive(api, PoseidonPrimitive)
+	api.AssertIsEqual(circuit.Hash, hashItems(api, circuit.Inputs))
+	return nil
+}
+
+// TestPoseidon checks the hash against the vectors of circomlib
+func TestPoseidon(t *testing.T) {
+	for _, c := range []struct {
+		n    int
+		hash string
+	}{
+		{1, "0x29176100eaa962bdc1fe6c654d6a3c130e96a4d1168b33848b897dc502820133"},
+		{2, "0x115cc0f5e7d690413df64c6b9662e9cf2a3617f2743245519e19607a4417189a"},
+		{4, "0x299c867db6c1fdd79dcefa40e4510b9837e60ebb1ce0663dbaa525df65250465"},
+		{40, ""}, // chained
+	} {
+		inputs := make([]*big.Int, c.n)
+		assignment := PoseidonCircuit{Inputs: make([]frontend.Variable, c.n)}
+		for i := range inputs {
+			inputs[i] = big.NewInt(int64(i + 1))
+			assignment.Inputs[i] = inputs[i]
+		}
+		hash := PoseidonPrimitive.HashOn(ecc.BN254, inputs...)
+		if want, _ := new(big.Int).SetString(c.hash, 0); c.hash != "" && hash.Cmp(want) != 0 {
+			t.Fatalf("poseidon of %d inputs: got %x", c.n, hash)
+		}
+		assignment.Hash = hash
+		if err := test.IsSolved(&PoseidonCircuit{Inputs: make([]frontend.Variable, c.n)}, &assignment, ecc.BN254.ScalarField()); err != nil {
+			t.Fatalf("%d inputs: %v", c.n, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package circuit
+
+import (
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+)
+
+// Primitive is the hash and keyed function the checks of a circuit are built on: the record
+// tag, the key commitment, the Merkle sets and the hashes verifying hints hash with it, and the
+// key stream of the encryption is Encrypt(key, nonce||i). MiMC is the default, a circuit picks
+// another one with WithPrimitive.
+type Primitive interface {
+	String() string
+	// Hash and Encrypt run in a circuit
+	Hash(api frontend.API, inputs []frontend.Variable) frontend.Variable
+	Encrypt(api frontend.API, key frontend.Variable, message frontend.Variable) frontend.Variable
+	// HashOn and EncryptOn are their native counterparts on the scalar field of curve
+	HashOn(curve ecc.ID, inputs ...*big.Int) *big.Int
+	EncryptOn(curve ecc.ID, key *big.Int, message *big.Int) *big.Int
+}
+
+var (
+	// MimcPrimitive is MiMC on every curve of Curves: the hash of gnark's std/hash/mimc and
+	// the block cipher of encryptMimc
+	MimcPrimitive Primitive = mimcPrimitive{}
+	// PoseidonPrimitive is circomlib's Poseidon, on BN254 only. Up to 16 inputs are hashed at
+	// once as in circomlib, longer inputs are chained 15 at a time after the first 16.
+	// Encrypt(key, message) is poseidon(key, message).
+	PoseidonPrimitive Primitive = poseidonPrimitive{}
+)
+
+// optionAPI is a frontend.API carrying the options of the checks of this package, see
+// WithPrimitive and WithCanonicalJSON, and the reporter of DiagnoseEdit
+type optionAPI struct {
+	frontend.API
+	primitive Primitive
+	canonical bool
+	reporter  func(RuleResult)
+}
+
+func optionsOf(api frontend.API) optionAPI {
+	if o, ok := api.(optionAPI); ok {
+		return o
+	}
+	return optionAPI{API: api, primitive: MimcPrimitive}
+}
+
+// WithPrimitive returns api with which the checks of this package use p instead of MiMC, e.g.
+//
+//	func (c *EditCircuit) Define(api frontend.API) error {
+//		api = circuit.WithPrimitive(api, circuit.PoseidonPrimitive)
+//		...
+func WithPrimitive(api frontend.API, p Primitive) frontend.API {
+	o := optionsOf(api)
+	o.primitive = p
+	return o
+}
+
+func primitiveOf(api frontend.API) Primitive {
+	return optionsOf(api).primitive
+}
+
+// hashItems hashes inputs with the primitive of the circuit
+func hashItems(api frontend.API, inputs []frontend.Variable) frontend.Variable {
+	return primitiveOf(api).Hash(api, inputs)
+}
+
+// encryptBlock is the keyed function of the primitive of the circuit
+func encryptBlock(api frontend.API, key frontend.Variable, message frontend.Variable) frontend.Variable {
+	return primitiveOf(api).Encrypt(api, key, message)
+}
+
+type mimcPrimitive struct{}
+
+func (mimcPrimitive) String() string {
+	return "mimc"
+}
+
+func (mimcPrimitive) Hash(api frontend.API, inputs []frontend.Variable) frontend.Variable {
+	return mimcHash(api, inputs)
+}
+
+func (mimcPrimitive) Encrypt(api frontend.API, key frontend.Variable, message frontend.Variable) frontend.Variable {
+	return encryptMimc(api, key, message)
+}
+
+func (mimcPrimitive) HashOn(curve ecc.ID, inputs ...*big.Int) *big.Int {
+	h, ok := mimcHashes[curve]
+	if !ok {
+		panic("unknown curve id")
+	}
+	size := (curve.ScalarField().BitLen() + 7) / 8
+	mimc := h.New()
+	for _, x := range inputs {
+		mimc.Write(x.FillBytes(make([]byte, size)))
+	}
+	return new(big.Int).SetBytes(mimc.Sum(nil))
+}
+
+func (mimcPrimitive) EncryptOn(curve ecc.ID, key *big.Int, message *big.Int) *big.Int {
+	return EncryptMimc(curve, key, message)
+}
+
+type poseidonPrimitive struct{}
+
+func (poseidonPrimitive) String() string {
+	return "poseidon"
+}
+
+func (poseidonPrimitive) Hash(api frontend.API, inputs []frontend.Variable) frontend.Variable {
+	n := len(inputs)
+	if n > poseidonMaxInputs {
+		n = poseidonMaxInputs
+	}
+	res := poseidon(api, inputs[:n])
+	for inputs = inputs[n:]; len(inputs) > 0; inputs = inputs[n:] {
+		n = len(inputs)
+		if n > poseidonMaxInputs-1 {
+			n = poseidonMaxInputs - 1
+		}
+		res = poseidon(api, append([]frontend.Variable{res}, inputs[:n]...))
+	}
+	return res
+}
+
+func (poseidonPrimitive) Encrypt(api frontend.API, key frontend.Variable, message frontend.Variable) frontend.Variable {
+	return poseidon(api, []frontend.Variable{key, message})
+}
+
+func (poseidonPrimitive) HashOn(curve ecc.ID, inputs ...*big.Int) *big.Int {
+	if curve != ecc.BN254 {
+		panic("Poseidon is only defined on the scalar field of BN254")
+	}
+	elements := make([]fr.Element, len(inputs))
+	for i := range inputs {
+		elements[i].SetBigInt(inputs[i])
+	}
+	n := len(elements)
+	if n > poseidonMaxInputs {
+		n = poseidonMaxInputs
+	}
+	res := poseidonFr(elements[:n]...)
+	for elements = elements[n:]; len(elements) > 0; elements = elements[n:] {
+		n = len(elements)
+		if n > poseidonMaxInputs-1 {
+			n = poseidonMaxInputs - 1
+		}
+		res = poseidonFr(append([]fr.Element{res}, elements[:n]...)...)
+	}
+	return res.BigInt(new(big.Int))
+}
+
+func (p poseidonPrimitive) EncryptOn(curve ecc.ID, key *big.Int, message *big.Int) *big.Int {
+	return p.HashOn(curve, key, message)
+}
@@ -0,0 +1,93 @@
+package circuit
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Nullus-Labs/IDEA-DAC/dfa"
+	"github.com/consensys/gnark/frontend"
+)
+
+// RuleKind is the edit bound enforced on a field
+type RuleKind int
+
+const (
+	RuleImmutable     RuleKind = iota // new value equals old value
+	RuleAppendOnly                    // old elements are kept, new ones may only be appended
+	RuleOneOfSet                      // new value is one of Set
+	RuleWithinRange                   // Range[0] <= new value <= Range[1]
+	RuleTimeInRange                   // new End is more than MinDuration after new Start, the field being shaped like TimeRange
+	RuleCertainFormat                 // new value meets Format
+	RuleMerkleSet                     // new value is a leaf of the Merkle set of root MerkleRoot
+	RuleRegexFormat                   // the whole new value matches Regex
+)
+
+// Rule binds an edit bound to the field found at Path in a record struct
+type Rule struct {
+	Path        string // dot separated keys, e.g. "LatestVaccine.Dosage", see jsonKey
+	Kind        RuleKind
+	Set         []String             // RuleOneOfSet
+	Range       [2]frontend.Variable // RuleWithinRange, [0] lowerbound, [1] upperbound
+	MinDuration frontend.Variable    // RuleTimeInRange, in seconds
+	Format      []frontend.Variable  // RuleCertainFormat, see checkFormat
+	MerkleRoot  frontend.Variable    // RuleMerkleSet, see MerkleSet
+	MerkleProof MerkleProof          // RuleMerkleSet, private, see WithMerkleProofs
+	Regex       *dfa.DFA             // RuleRegexFormat, fixed when the circuit is compiled
+}
+
+// Limit is implemented by the edit bounds of a record type, e.g. PhdLimit
+type Limit interface {
+	Rules(api frontend.API) []Rule
+}
+
+// check returns 1 if the edit from oldContent to newContent satisfies the rule, 0 otherwise
+func (r Rule) check(api frontend.API, oldContent interface{}, newContent interface{}) frontend.Variable {
+	oldValue := fieldByPath(oldContent, r.Path)
+	newValue := fieldByPath(newContent, r.Path)
+	switch r.Kind {
+	case RuleImmutable:
+		return isEqualInterface(api, oldValue, newValue)
+	case RuleAppendOnly:
+		return checkAppendOnly(api, toArray(api, oldValue), toArray(api, newValue))
+	case RuleOneOfSet:
+		return checkOneOfSet(api, len(r.Set), r.Set, newValue.(String))
+	case RuleWithinRange:
+		return checkWithinRange(api, r.Range[0], r.Range[1], newValue.(Integer).X)
+	case RuleTimeInRange:
+		start := reflect.ValueOf(newValue).FieldByName("Start").Interface().(Integer)
+		end := reflect.ValueOf(newValue).FieldByName("End").Interface().(Integer)
+		return checkTimeInRange(api, r.MinDuration, start.X, end.X)
+	case RuleCertainFormat:
+		return checkFormat(api, len(r.Format), r.Format, newValue.(String))
+	case RuleMerkleSet:
+		return checkMerkleSet(api, r.MerkleRoot, r.MerkleProof, newValue.(String))
+	case RuleRegexFormat:
+		return checkRegex(api, r.Regex, newValue.(String))
+	default:
+		panic(fmt.Sprintf("Invalid rule kind %d", r.Kind))
+	}
+}
+
+// fieldByPath returns the field of content at path, each key of path being matched against the
+// keys the fields are encoded with
+func fieldByPath(content interface{}, path string) interface{} {
+	v := reflect.ValueOf(content)
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() != reflect.Struct {
+			panic(fmt.Sprintf("Invalid path %s", path))
+		}
+		next := reflect.Value{}
+		for i := 0; i < v.NumField(); i++ {
+			if key, _, ok := jsonKey(v.Type().Field(i)); ok && key == name {
+				next = v.Field(i)
+				break
+			}
+		}
+		if !next.IsValid() {
+			panic(fmt.Sprintf("Invalid path %s", path))
+		}
+		v = next
+	}
+	return v.Interface()
+}
@@ -0,0 +1,120 @@
+package circuit
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	eddsabls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/twistededwards/eddsa"
+	eddsabls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/twistededwards/eddsa"
+	eddsabn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	eddsabw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/twistededwards/eddsa"
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	"github.com/consensys/gnark-crypto/signature"
+	"github.com/consensys/gnark-crypto/signature/eddsa"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/twistededwards"
+	"github.com/consensys/gnark/std/hash/mimc"
+	stdeddsa "github.com/consensys/gnark/std/signature/eddsa"
+)
+
+// issuerCurves are the twisted Edwards curves defined over the scalar field of each curve,
+// BabyJubJub for BN254 and Jubjub for BLS12-381, on which issuers sign records
+var issuerCurves = map[ecc.ID]struct {
+	id        tedwards.ID
+	publicKey func() signature.PublicKey
+}{
+	ecc.BN254:     {tedwards.BN254, func() signature.PublicKey { return new(eddsabn254.PublicKey) }},
+	ecc.BLS12_381: {tedwards.BLS12_381, func() signature.PublicKey { return new(eddsabls12381.PublicKey) }},
+	ecc.BLS12_377: {tedwards.BLS12_377, func() signature.PublicKey { return new(eddsabls12377.PublicKey) }},
+	ecc.BW6_761:   {tedwards.BW6_761, func() signature.PublicKey { return new(eddsabw6761.PublicKey) }},
+}
+
+// IssuerCurve returns the twisted Edwards curve of the issuer signatures of circuits compiled on
+// the scalar field of curve
+func IssuerCurve(curve ecc.ID) tedwards.ID {
+	c, ok := issuerCurves[curve]
+	if !ok {
+		panic("unknown curve id")
+	}
+	return c.id
+}
+
+// checkIssuerSignature asserts that signature is the EdDSA signature by issuer of digest, the
+// digest of the issued record, see History. The challenge of the signature is hashed with MiMC
+// whatever the primitive of the circuit, as gnark-crypto signs.
+func checkIssuerSignature(api frontend.API, issuer stdeddsa.PublicKey, signature stdeddsa.Signature, digest frontend.Variable) {
+	id, err := curveOf(api.Compiler().Field())
+	if err != nil {
+		panic(err)
+	}
+	curve, err := twistededwards.NewEdCurve(api, IssuerCurve(id))
+	if err != nil {
+		panic(err)
+	}
+	hash, err := mimc.NewMiMC(api)
+	if err != nil {
+		panic(err)
+	}
+	api.AssertIsEqual(verifyEdDSA(curve, signature, digest, issuer, &hash), 1)
+}
+
+// verifyEdDSA returns 1 if sig is the signature by pubKey of msg, 0 otherwise. It is
+// stdeddsa.Verify returning its result instead of asserting it.
+func verifyEdDSA(curve twistededwards.Curve, sig stdeddsa.Signature, msg frontend.Variable, pubKey stdeddsa.PublicKey, hash *mimc.MiMC) frontend.Variable {
+	api := curve.API()
+	hash.Write(sig.R.X, sig.R.Y, pubKey.A.X, pubKey.A.Y, msg)
+	hRAM := hash.Sum()
+
+	base := twistededwards.Point{X: curve.Params().Base[0], Y: curve.Params().Base[1]}
+	// [cofactor]([S]G-[H(R,A,M)]A-R) is the identity
+	q := curve.DoubleBaseScalarMul(base, curve.Neg(pubKey.A), sig.S, hRAM)
+	curve.AssertIsOnCurve(q)
+	q = curve.Add(curve.Neg(q), sig.R)
+	for c := curve.Params().Cofactor.Uint64(); c > 1; c >>= 1 {
+		q = curve.Double(q)
+	}
+	return and(api, isEqual(api, q.X, 0), isEqual(api, q.Y, 1))
+}
+
+func EmptyIssuerKey() stdeddsa.PublicKey {
+	return stdeddsa.PublicKey{A: twistededwards.Point{X: 0, Y: 0}}
+}
+
+func EmptySignature() stdeddsa.Signature {
+	return stdeddsa.Signature{R: twistededwards.Point{X: 0, Y: 0}, S: 0}
+}
+
+// NewIssuer draws the EdDSA key of an issuer for circuits compiled on the scalar field of curve
+func NewIssuer(curve ecc.ID) (signature.Signer, error) {
+	return eddsa.New(IssuerCurve(curve), rand.Reader)
+}
+
+// NewIssuerPublicKey returns an empty public key of an issuer, to be read with SetBytes
+func NewIssuerPublicKey(curve ecc.ID) signature.PublicKey {
+	c, ok := issuerCurves[curve]
+	if !ok {
+		panic("unknown curve id")
+	}
+	return c.publicKey()
+}
+
+// Sign is the signature by issuer of the digest of ct, checked by the edit circuits
+func (c Cipher) Sign(issuer signature.Signer, ct *Ciphertext) ([]byte, error) {
+	return issuer.Sign(c.digestBytes(ct), mimcHashes[c.Curve].New())
+}
+
+// VerifySignature is the native counterpart of checkIssuerSignature
+func (c Cipher) VerifySignature(issuer signature.PublicKey, sig []byte, ct *Ciphertext) (bool, error) {
+	return issuer.Verify(sig, c.digestBytes(ct), mimcHashes[c.Curve].New())
+}
+
+func (c Cipher) digestBytes(ct *Ciphertext) []byte {
+	size := (c.Curve.ScalarField().BitLen() + 7) / 8
+	return c.RecordDigest(ct.Nonce, ct.Blocks).FillBytes(make([]byte, size))
+}
+
+// RecordDigest is the native counterpart of recordDigest
+func (c Cipher) RecordDigest(nonce *big.Int, blocks []*big.Int) *big.Int {
+	return c.Primitive.HashOn(c.Curve, append([]*big.Int{nonce}, blocks...)...)
+}
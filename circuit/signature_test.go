@@ -0,0 +1,68 @@
+package circuit
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/signature/eddsa"
+	"github.com/consensys/gnark/test"
+)
+
+type IssuerSignatureCircuit struct {
+	IssuerKey eddsa.PublicKey `gnark:",public"`
+	Signature eddsa.Signature
+	Record    Record `gnark:",public"`
+}
+
+func (circuit *IssuerSignatureCircuit) Define(api frontend.API) error {
+	checkIssuerSignature(api, circuit.IssuerKey, circuit.Signature, recordDigest(api, circuit.Record))
+	return nil
+}
+
+func TestIssuerSignature(t *testing.T) {
+	key := big.NewInt(1111)
+	for _, curve := range Curves() {
+		cipher := Cipher{curve, MimcPrimitive}
+		enc, err := cipher.Encrypt([]byte(`{"Name":"Alice"}`), key, big.NewInt(1), 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		issuer, err := NewIssuer(curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := cipher.Sign(issuer, enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicKey := NewIssuerPublicKey(curve)
+		if _, err := publicKey.SetBytes(issuer.Public().Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := cipher.VerifySignature(publicKey, sig, enc); !ok || err != nil {
+			t.Fatalf("%s: the signature does not verify: %v", curve, err)
+		}
+
+		assignment := IssuerSignatureCircuit{Record: enc.Record()}
+		assignment.IssuerKey.Assign(IssuerCurve(curve), issuer.Public().Bytes())
+		assignment.Signature.Assign(IssuerCurve(curve), sig)
+		if err := test.IsSolved(&IssuerSignatureCircuit{Record: EmptyRecord(2)}, &assignment, curve.ScalarField()); err != nil {
+			t.Errorf("%s: %v", curve, err)
+		}
+
+		enc.Blocks[1] = big.NewInt(1)
+		if ok, _ := cipher.VerifySignature(publicKey, sig, enc); ok {
+			t.Fatalf("%s: the signature verifies a tampered record", curve)
+		}
+		assignment.Record = enc.Record()
+		if err := test.IsSolved(&IssuerSignatureCircuit{Record: EmptyRecord(2)}, &assignment, curve.ScalarField()); err == nil {
+			t.Errorf("%s: the circuit accepts a tampered record", curve)
+		}
+		assignment.Signature = EmptySignature()
+		if err := test.IsSolved(&IssuerSignatureCircuit{Record: EmptyRecord(2)}, &assignment, curve.ScalarField()); err == nil {
+			t.Errorf("%s: the circuit accepts a record without signature", curve)
+		}
+
+	}
+}
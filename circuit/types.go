@@ -7,23 +7,23 @@ const OneYearUnix = 31536000
 
 // Dict
 type PhDProfile struct {
-	Status       String              //One of the Set
-	ProgramYear  Integer             //Number within range
-	StudentID    String              //meet format
-	Publications []Publication //Append only
-	Duration     TimeRange           //time sensitive
+	Status       String        `json:"status"`       //One of the Set
+	ProgramYear  Integer       `json:"programYear"`  //Number within range
+	StudentID    String        `json:"studentID"`    //meet format
+	Publications []Publication `json:"publications"` //Append only
+	Duration     TimeRange     `json:"duration"`     //time sensitive
 }
 
 // Dict
 type Publication struct {
-	Title String
-	Year  Integer
+	Title String  `json:"title"`
+	Year  Integer `json:"year"`
 }
 
 // Dict
 type TimeRange struct {
-	Start Integer
-	End   Integer
+	Start Integer `json:"start"`
+	End   Integer `json:"end"`
 }
 
 type CovidRecord struct {
@@ -55,3 +55,28 @@ type CovidLimit struct {
 	CoverageMaxEndDate        Integer //vaccine can only coverage within a certain time
 	Format                    []frontend.Variable
 }
+
+func (l PhdLimit) Rules(api frontend.API) []Rule {
+	return []Rule{
+		{Path: "publications", Kind: RuleAppendOnly},
+		{Path: "status", Kind: RuleOneOfSet, Set: l.StatusSet[:]},
+		{Path: "programYear", Kind: RuleWithinRange, Range: l.YearRange},
+		{Path: "duration", Kind: RuleTimeInRange, MinDuration: api.Mul(l.TimeMinRange.X, OneYearUnix)},
+		{Path: "studentID", Kind: RuleCertainFormat, Format: l.Format},
+		{Path: "studentID", Kind: RuleImmutable},
+	}
+}
+
+// PhdACLPaths are the fields of a PhDProfile whose editors are checked by CheckEditors
+var PhdACLPaths = []string{"status", "programYear", "duration", "studentID", "publications"}
+
+func (l CovidLimit) Rules(api frontend.API) []Rule {
+	return []Rule{
+		{Path: "LatestVaccine.VaccineType", Kind: RuleOneOfSet, Set: l.VaccineTypeSet},
+		{Path: "LatestVaccine.Dosage", Kind: RuleWithinRange, Range: [2]frontend.Variable{0, l.DosageMax}},
+		{Path: "CovidTest", Kind: RuleAppendOnly},
+		{Path: "CovidTestNumber", Kind: RuleCertainFormat, Format: l.Format},
+		{Path: "MedicalInsuranceStatus", Kind: RuleOneOfSet, Set: l.MedicalInsuranceStatusSet},
+		{Path: "CoverageEndDate", Kind: RuleWithinRange, Range: [2]frontend.Variable{0, l.CoverageMaxEndDate.X}},
+	}
+}
@@ -1,9 +1,11 @@
 package circuit
 
 import (
+	"fmt"
 	"math/big"
+	"strings"
+	"unicode/utf8"
 
-	bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/std/hash/mimc"
 )
@@ -133,18 +135,18 @@ func batchMul(api frontend.API, a []frontend.Variable) frontend.Variable {
 
 func simpleHash(api frontend.API, items []frontend.Variable, itemBit int) frontend.Variable {
 	items = mergeItems(api, items, itemBit)
-	return mimcBinaryTree(api, items)
+	return hashBinaryTree(api, items)
 }
 
-func mimcBinaryTree(api frontend.API, items []frontend.Variable) frontend.Variable {
+func hashBinaryTree(api frontend.API, items []frontend.Variable) frontend.Variable {
 	if len(items) == 1 {
 		return items[0]
 	}
 	if len(items) == 2 {
-		return mimcHash(api, items)
+		return hashItems(api, items)
 	}
 	mid := (len(items) + 1) / 2
-	return mimcHash(api, []frontend.Variable{mimcBinaryTree(api, items[:mid]), mimcBinaryTree(api, items[mid:])})
+	return hashItems(api, []frontend.Variable{hashBinaryTree(api, items[:mid]), hashBinaryTree(api, items[mid:])})
 }
 
 func mimcHash(api frontend.API, inputs []frontend.Variable) frontend.Variable {
@@ -201,7 +203,7 @@ func legitimateCheck(api frontend.API, a []frontend.Variable) {
 	// Either is true
 	lIsZero := api.IsZero(l)
 	api.AssertIsEqual(api.Or(hasZero, lIsZero), 1)
-	api.AssertIsEqual(api.And(hasZero, lIsZero), 0)
+	api.AssertIsEqual(and(api, hasZero, lIsZero), 0)
 }
 
 func rangeCheckString(api frontend.API, a []frontend.Variable) {
@@ -276,6 +278,18 @@ func isLessOrEqual(api frontend.API, a frontend.Variable, b frontend.Variable) f
 	return boolNeg(api, isGreater(api, a, b))
 }
 
+// and is api.And, which the PLONK builder can not apply to two constants
+func and(api frontend.API, a frontend.Variable, b frontend.Variable) frontend.Variable {
+	_, aConstant := api.Compiler().ConstantValue(a)
+	_, bConstant := api.Compiler().ConstantValue(b)
+	if aConstant && bConstant {
+		api.AssertIsBoolean(a)
+		api.AssertIsBoolean(b)
+		return api.Mul(a, b)
+	}
+	return api.And(a, b)
+}
+
 func boolNeg(api frontend.API, a frontend.Variable) frontend.Variable {
 	return api.Sub(1, a)
 }
@@ -307,26 +321,108 @@ func withinBinary(api frontend.API, v frontend.Variable, n int) frontend.Variabl
 	return isEqual(api, Σbi, v)
 }
 
-func CommitMiMC(msg []byte) []byte {
-	if len(msg) > 32 {
-		panic("Message Too Long")
-	}
-	inputs := make([]byte, 32-len(msg))
-	for i := range inputs {
-		inputs[i] = 0
+// StringToAscii returns the bytes of the UTF-8 encoding of input
+func StringToAscii(input string) []int64 {
+	var res []int64
+	for i := 0; i < len(input); i++ {
+		res = append(res, int64(input[i]))
 	}
-	inputs = append(inputs, msg...)
-	mimc := bn254.NewMiMC()
-	mimc.Write(inputs)
-	sum := mimc.Sum([]byte{})
-	return sum
+	return res
 }
 
-func StringToAscii(input string) []int64 {
-	var res []int64
-	//res = append(res, int64(len(input)))
-	for _, c := range input {
-		res = append(res, int64(c))
+// NewString pads input to a String of the given capacity, String[0] being the length of input
+// in bytes. input must be valid UTF-8.
+func NewString(input string, capacity int) String {
+	if !utf8.ValidString(input) {
+		panic("invalid UTF-8 string")
+	}
+	ascii := StringToAscii(input)
+	if capacity < len(ascii)+1 {
+		panic("Invalid Capacity")
+	}
+	res := make(String, capacity)
+	res[0] = len(ascii)
+	for i := 1; i < capacity; i++ {
+		if i <= len(ascii) {
+			res[i] = ascii[i-1]
+		} else {
+			res[i] = DUMMY
+		}
 	}
 	return res
 }
+
+func EmptyString(capacity int) String {
+	return NewString("", capacity)
+}
+
+func NewInteger(x int64, maxDigit int) Integer {
+	return Integer{
+		X:        x,
+		MaxDigit: maxDigit}
+}
+
+func NewSignedInteger(x int64, maxDigit int) Integer {
+	return Integer{
+		X:        x,
+		MaxDigit: maxDigit,
+		Signed:   true}
+}
+
+// NewDecimal parses the JSON number text, e.g. "-12.50", as a signed Integer with scale digits
+// after the decimal point. It panics unless text is written as encodeNumber writes it, with
+// exactly scale decimals, no exponent and no superfluous zero.
+func NewDecimal(text string, maxDigit int, scale int) Integer {
+	digits := strings.TrimPrefix(text, "-")
+	integral, fraction := digits, ""
+	if scale > 0 {
+		parts := strings.SplitN(digits, ".", 2)
+		if len(parts) != 2 {
+			panic(fmt.Sprintf("%s has no decimal point", text))
+		}
+		integral, fraction = parts[0], parts[1]
+	}
+	valid := len(integral) > 0 && len(integral) <= maxDigit-scale && len(fraction) == scale
+	valid = valid && (integral == "0" || integral[0] != '0')
+	x, ok := new(big.Int).SetString(integral+fraction, 10)
+	if !valid || !ok || strings.ContainsAny(integral+fraction, "+-") || (x.Sign() == 0 && digits != text) {
+		panic(fmt.Sprintf("%s is not a number of %d digits with %d decimals", text, maxDigit, scale))
+	}
+	if digits != text {
+		x.Neg(x)
+	}
+	return Integer{
+		X:        x,
+		MaxDigit: maxDigit,
+		Signed:   true,
+		Scale:    scale}
+}
+
+func NewBool(b bool) Bool {
+	if b {
+		return Bool{X: 1}
+	}
+	return Bool{X: 0}
+}
+
+// NewOptionalInteger is null if x is nil, as encoding/json marshals a nil *int64
+func NewOptionalInteger(x *int64, maxDigit int) OptionalInteger {
+	if x == nil {
+		return OptionalInteger{IsNull: 1, Value: NewInteger(0, maxDigit)}
+	}
+	return OptionalInteger{IsNull: 0, Value: NewInteger(*x, maxDigit)}
+}
+
+func NewOptionalString(s *string, capacity int) OptionalString {
+	if s == nil {
+		return OptionalString{IsNull: 1, Value: EmptyString(capacity)}
+	}
+	return OptionalString{IsNull: 0, Value: NewString(*s, capacity)}
+}
+
+func NewOptionalBool(b *bool) OptionalBool {
+	if b == nil {
+		return OptionalBool{IsNull: 1, Value: NewBool(false)}
+	}
+	return OptionalBool{IsNull: 0, Value: NewBool(*b)}
+}
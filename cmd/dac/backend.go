@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+)
+
+// key is a proving key, verifying key or proof of either backend
+type key interface {
+	io.WriterTo
+	io.ReaderFrom
+}
+
+// backend is the proof system picked with -backend. Groth16 needs a setup per circuit, PLONK
+// runs its setup from a universal KZG SRS, which must also be given to prove and verify since
+// the keys do not embed it.
+type backend struct {
+	name     string
+	builder  frontend.NewBuilder
+	newCS    func() constraint.ConstraintSystem
+	setup    func(cs constraint.ConstraintSystem, srs kzg.SRS) (key, key, error)
+	newPK    func(srs kzg.SRS) key
+	newVK    func(srs kzg.SRS) key
+	prove    func(cs constraint.ConstraintSystem, pk key, w witness.Witness) (key, error)
+	newProof func() key
+	verify   func(proof key, vk key, w witness.Witness) error
+}
+
+var backends = map[string]*backend{
+	"groth16": {
+		name:    "groth16",
+		builder: r1cs.NewBuilder,
+		newCS:   func() constraint.ConstraintSystem { return groth16.NewCS(ecc.BN254) },
+		setup: func(cs constraint.ConstraintSystem, _ kzg.SRS) (key, key, error) {
+			return groth16.Setup(cs)
+		},
+		newPK: func(kzg.SRS) key { return groth16.NewProvingKey(ecc.BN254) },
+		newVK: func(kzg.SRS) key { return groth16.NewVerifyingKey(ecc.BN254) },
+		prove: func(cs constraint.ConstraintSystem, pk key, w witness.Witness) (key, error) {
+			return groth16.Prove(cs, pk.(groth16.ProvingKey), w)
+		},
+		newProof: func() key { return groth16.NewProof(ecc.BN254) },
+		verify: func(proof key, vk key, w witness.Witness) error {
+			return groth16.Verify(proof.(groth16.Proof), vk.(groth16.VerifyingKey), w)
+		},
+	},
+	"plonk": {
+		name:    "plonk",
+		builder: scs.NewBuilder,
+		newCS:   func() constraint.ConstraintSystem { return plonk.NewCS(ecc.BN254) },
+		setup: func(cs constraint.ConstraintSystem, srs kzg.SRS) (key, key, error) {
+			return plonk.Setup(cs, srs)
+		},
+		newPK: func(srs kzg.SRS) key { return &plonkKey{key: plonk.NewProvingKey(ecc.BN254), srs: srs} },
+		newVK: func(srs kzg.SRS) key { return &plonkKey{key: plonk.NewVerifyingKey(ecc.BN254), srs: srs} },
+		prove: func(cs constraint.ConstraintSystem, pk key, w witness.Witness) (key, error) {
+			return plonk.Prove(cs, pk.(*plonkKey).key.(plonk.ProvingKey), w)
+		},
+		newProof: func() key { return plonk.NewProof(ecc.BN254) },
+		verify: func(proof key, vk key, w witness.Witness) error {
+			return plonk.Verify(proof.(plonk.Proof), vk.(*plonkKey).key.(plonk.VerifyingKey), w)
+		},
+	},
+}
+
+func getBackend(name string) (*backend, error) {
+	b, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q, expected groth16 or plonk", name)
+	}
+	return b, nil
+}
+
+// loadSRS reads the KZG SRS of the PLONK backend, Groth16 does not use one
+func (b *backend) loadSRS(path string) (kzg.SRS, error) {
+	if b.name != "plonk" {
+		return nil, nil
+	}
+	if path == "" {
+		return nil, errors.New("the plonk backend needs -srs")
+	}
+	srs := kzg.NewSRS(ecc.BN254)
+	if err := readFile(path, srs); err != nil {
+		return nil, err
+	}
+	return srs, nil
+}
+
+// plonkKey attaches the SRS to a PLONK key once it is read
+type plonkKey struct {
+	key interface {
+		key
+		InitKZG(srs kzg.SRS) error
+	}
+	srs kzg.SRS
+}
+
+func (k *plonkKey) ExportSolidity(w io.Writer) error {
+	return k.key.(plonk.VerifyingKey).ExportSolidity(w)
+}
+
+func (k *plonkKey) WriteTo(w io.Writer) (int64, error) {
+	return k.key.WriteTo(w)
+}
+
+func (k *plonkKey) ReadFrom(r io.Reader) (int64, error) {
+	n, err := k.key.ReadFrom(r)
+	if err != nil {
+		return n, err
+	}
+	return n, k.key.InitKZG(k.srs)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+)
+
+// cubicCircuit asserts that X**3 + X + 5 == Y
+type cubicCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *cubicCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(c.Y, api.Add(x3, c.X, 5))
+	return nil
+}
+
+// TestBackendRoundTrip runs the files of the setup, prove and verify commands through both
+// backends, the PLONK keys being read back with the SRS written by the srs command
+func TestBackendRoundTrip(t *testing.T) {
+	for _, name := range []string{"groth16", "plonk"} {
+		t.Run(name, func(t *testing.T) {
+			b, err := getBackend(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir := t.TempDir()
+			csPath, srsPath := filepath.Join(dir, "cubic.cs"), filepath.Join(dir, "cubic.srs")
+			pkPath, vkPath, proofPath := filepath.Join(dir, "cubic.pk"), filepath.Join(dir, "cubic.vk"), filepath.Join(dir, "cubic.proof")
+
+			ccs, err := frontend.Compile(ecc.BN254.ScalarField(), b.builder, &cubicCircuit{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writeFile(csPath, ccs); err != nil {
+				t.Fatal(err)
+			}
+			args := []string{"-backend", name, "-cs", csPath, "-pk", pkPath, "-vk", vkPath}
+			if name == "plonk" {
+				if err := srs([]string{"-cs", csPath, "-o", srsPath}); err != nil {
+					t.Fatal(err)
+				}
+				if err := setup(args); err == nil {
+					t.Fatal("expected the plonk setup without -srs to fail")
+				}
+				args = append(args, "-srs", srsPath)
+			}
+			if err := setup(args); err != nil {
+				t.Fatal(err)
+			}
+
+			kzgSRS, err := b.loadSRS(srsPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cs := b.newCS()
+			if err := readFile(csPath, cs); err != nil {
+				t.Fatal(err)
+			}
+			pk, vk := b.newPK(kzgSRS), b.newVK(kzgSRS)
+			if err := readFile(pkPath, pk); err != nil {
+				t.Fatal(err)
+			}
+			if err := readFile(vkPath, vk); err != nil {
+				t.Fatal(err)
+			}
+
+			w, err := frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BN254.ScalarField())
+			if err != nil {
+				t.Fatal(err)
+			}
+			proof, err := b.prove(cs, pk, w)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writeFile(proofPath, proof); err != nil {
+				t.Fatal(err)
+			}
+			proof = b.newProof()
+			if err := readFile(proofPath, proof); err != nil {
+				t.Fatal(err)
+			}
+			public, err := w.Public()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := b.verify(proof, vk, public); err != nil {
+				t.Fatal(err)
+			}
+
+			wrong, err := frontend.NewWitness(&cubicCircuit{Y: 36}, ecc.BN254.ScalarField(), frontend.PublicOnly())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := b.verify(proof, vk, wrong); err == nil {
+				t.Fatal("expected the proof to be rejected for another public input")
+			}
+		})
+	}
+}
@@ -0,0 +1,665 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/Nullus-Labs/IDEA-DAC/aggregate"
+	"github.com/Nullus-Labs/IDEA-DAC/batch"
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/Nullus-Labs/IDEA-DAC/history"
+	"github.com/Nullus-Labs/IDEA-DAC/phd"
+	"github.com/Nullus-Labs/IDEA-DAC/policy"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	"github.com/consensys/gnark-crypto/signature"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+func compile(args []string) error {
+	flags := flag.NewFlagSet("compile", flag.ExitOnError)
+	maxPub := flags.Int("maxpub", 3, "maximum number of publications")
+	backendName := flags.String("backend", "groth16", "proof system, groth16 or plonk")
+	csPath := flags.String("cs", "phd.cs", "output constraint system")
+	flags.Parse(args)
+
+	b, err := getBackend(*backendName)
+	if err != nil {
+		return err
+	}
+	circ := phd.InitPhdEditCircuit(*maxPub)
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), b.builder, &circ)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Number of constraints:", cs.GetNbConstraints())
+	return writeFile(*csPath, cs)
+}
+
+func srs(args []string) error {
+	flags := flag.NewFlagSet("srs", flag.ExitOnError)
+	csPath := flags.String("cs", "phd.cs", "input PLONK constraint system")
+	out := flags.String("o", "phd.srs", "output KZG SRS")
+	flags.Parse(args)
+
+	cs := plonk.NewCS(ecc.BN254)
+	if err := readFile(*csPath, cs); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Warning: the SRS toxic waste is known to this process, use the SRS of a ceremony in production")
+	kzgSRS, err := test.NewKZGSRS(cs)
+	if err != nil {
+		return err
+	}
+	return writeFile(*out, kzgSRS)
+}
+
+func setup(args []string) error {
+	flags := flag.NewFlagSet("setup", flag.ExitOnError)
+	backendName := flags.String("backend", "groth16", "proof system, groth16 or plonk")
+	csPath := flags.String("cs", "phd.cs", "input constraint system")
+	srsPath := flags.String("srs", "", "input KZG SRS, plonk only")
+	pkPath := flags.String("pk", "phd.pk", "output proving key")
+	vkPath := flags.String("vk", "phd.vk", "output verifying key")
+	flags.Parse(args)
+
+	b, err := getBackend(*backendName)
+	if err != nil {
+		return err
+	}
+	kzgSRS, err := b.loadSRS(*srsPath)
+	if err != nil {
+		return err
+	}
+	cs := b.newCS()
+	if err := readFile(*csPath, cs); err != nil {
+		return err
+	}
+	pk, vk, err := b.setup(cs, kzgSRS)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(*pkPath, pk); err != nil {
+		return err
+	}
+	return writeFile(*vkPath, vk)
+}
+
+func prove(args []string) error {
+	flags := flag.NewFlagSet("prove", flag.ExitOnError)
+	maxPub := flags.Int("maxpub", 3, "maximum number of publications, as given to compile")
+	backendName := flags.String("backend", "groth16", "proof system, groth16 or plonk")
+	csPath := flags.String("cs", "phd.cs", "input constraint system")
+	srsPath := flags.String("srs", "", "input KZG SRS, plonk only")
+	pkPath := flags.String("pk", "phd.pk", "input proving key")
+	oldPath := flags.String("old", "oldProfile.json", "profile before the edit")
+	newPath := flags.String("new", "newProfile.json", "profile after the edit")
+	policyPath := flags.String("policy", "phdPolicy.json", "edit policy")
+	keyHex := flags.String("key", "", "encryption key, e.g. 0x52fd...")
+	blindingStr := flags.String("blinding", "", "blinding factor of the key commitment, random if empty")
+	oldNonceStr := flags.String("oldnonce", "", "nonce the old profile was encrypted and signed with")
+	newNonceStr := flags.String("newnonce", "", "nonce the new profile was approved with")
+	issuerPath := flags.String("issuer", "issuer.pub", "public key of the issuer of the old profile")
+	sigPath := flags.String("signature", "old.sig", "signature by the issuer of the profile issued at version 0 of the lineage")
+	editorsStr := flags.String("editors", "", "public key of the editor of every role of the policy, e.g. registrar=registrar.pub,student=student.pub")
+	approvalsStr := flags.String("approvals", "", "approvals of the edit by role, e.g. registrar=registrar.approval")
+	historyPath := flags.String("history", "", "lineage of the profile, extended with the new profile; a new lineage starts from the old profile if the file does not exist")
+	proofPath := flags.String("proof", "edit.proof", "output proof")
+	publicPath := flags.String("public", "edit.pub", "output public witness")
+	flags.Parse(args)
+
+	if *keyHex == "" {
+		return errors.New("missing -key")
+	}
+	if *oldNonceStr == "" {
+		return errors.New("missing -oldnonce, the nonce the old profile was signed with")
+	}
+	if *newNonceStr == "" {
+		return errors.New("missing -newnonce, the nonce the new profile was approved with")
+	}
+	editorPaths, err := parseRoleFiles(*editorsStr)
+	if err != nil {
+		return err
+	}
+	approvalPaths, err := parseRoleFiles(*approvalsStr)
+	if err != nil {
+		return err
+	}
+	if len(approvalPaths) == 0 {
+		return errors.New("missing -approvals")
+	}
+	issuer, err := readIssuerPublicKey(*issuerPath)
+	if err != nil {
+		return err
+	}
+	b, err := getBackend(*backendName)
+	if err != nil {
+		return err
+	}
+	kzgSRS, err := b.loadSRS(*srsPath)
+	if err != nil {
+		return err
+	}
+	key, err := new(fr.Element).SetString(*keyHex)
+	if err != nil {
+		return err
+	}
+
+	phdPolicy, err := policy.Load(*policyPath)
+	if err != nil {
+		return err
+	}
+	limit, err := phd.LimitFromPolicy(phdPolicy)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Policy %s version %d: %s\n", phdPolicy.Name, phdPolicy.Version, phdPolicy.Hash())
+	oldEnc, _, err := phd.ReadJSON(*oldPath)
+	if err != nil {
+		return err
+	}
+	newEnc, _, err := phd.ReadJSON(*newPath)
+	if err != nil {
+		return err
+	}
+	violations, err := policy.CheckJSON(phdPolicy.Rules, oldEnc, newEnc)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		for _, v := range violations {
+			fmt.Fprintln(os.Stderr, "Invalid edit:", v)
+		}
+		return errors.New("the edit breaks the policy")
+	}
+	editors := map[string]signature.PublicKey{}
+	for role, name := range editorPaths {
+		if editors[role], err = readIssuerPublicKey(name); err != nil {
+			return err
+		}
+	}
+	acl, err := phd.ACLFromPolicy(phdPolicy, editors)
+	if err != nil {
+		return err
+	}
+	var roles []string
+	var approvals []phd.Approval
+	for role := range approvalPaths {
+		if _, ok := phdPolicy.Role(role); !ok {
+			return fmt.Errorf("unknown role %s", role)
+		}
+	}
+	for i, r := range phdPolicy.Roles {
+		name, ok := approvalPaths[r.Name]
+		if !ok {
+			continue
+		}
+		approval, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		roles = append(roles, r.Name)
+		approvals = append(approvals, phd.Approval{Editor: i, Signature: approval})
+	}
+	if len(approvals) > phd.MaxApprovals {
+		return fmt.Errorf("%d approvals, at most %d are supported", len(approvals), phd.MaxApprovals)
+	}
+	oldRecord, err := policy.Decode(oldEnc)
+	if err != nil {
+		return err
+	}
+	newRecord, err := policy.Decode(newEnc)
+	if err != nil {
+		return err
+	}
+	uncovered, err := phdPolicy.Uncovered(circuit.PhdACLPaths, roles, oldRecord, newRecord)
+	if err != nil {
+		return err
+	}
+	if len(uncovered) > 0 {
+		return fmt.Errorf("no approving role may change %v", uncovered)
+	}
+
+	blinding, err := parseBlinding(*blindingStr)
+	if err != nil {
+		return err
+	}
+	oldNonce, err := parseNonce(*oldNonceStr)
+	if err != nil {
+		return err
+	}
+	newNonce, err := parseNonce(*newNonceStr)
+	if err != nil {
+		return err
+	}
+	if oldNonce.Cmp(newNonce) == 0 {
+		return errors.New("-newnonce must differ from -oldnonce, the keystream of a nonce is used once")
+	}
+	fmt.Printf("Blinding: %s\n", blinding)
+	fmt.Printf("Nonces: old %s, new %s\n", oldNonce, newNonce)
+	lineage, err := loadLineage(*historyPath, *oldPath, key, oldNonce)
+	if err != nil {
+		return err
+	}
+	oldCiphertext, err := phd.EncryptProfile(*oldPath, key, oldNonce)
+	if err != nil {
+		return err
+	}
+	if !lineage.IsHead(oldCiphertext) {
+		return fmt.Errorf("%s encrypted with -oldnonce %s is not version %d, the head of %s", *oldPath, oldNonce, lineage.Head().Version, *historyPath)
+	}
+	newCiphertext, err := phd.EncryptProfile(*newPath, key, newNonce)
+	if err != nil {
+		return err
+	}
+	head, next := lineage.Head(), lineage.Next(newCiphertext)
+	sig, err := os.ReadFile(*sigPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("History: version %d %s to version %d %s\n", head.Version, head.Digest, next.Version, next.Digest)
+	assignment, err := phd.GetAssignment(*oldPath, *newPath, limit, key, blinding, oldNonce, newNonce, issuer, sig, acl, approvals, lineage, *maxPub)
+	if err != nil {
+		return err
+	}
+	cs := b.newCS()
+	if err := readFile(*csPath, cs); err != nil {
+		return err
+	}
+	pk := b.newPK(kzgSRS)
+	if err := readFile(*pkPath, pk); err != nil {
+		return err
+	}
+	fullWitness, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return err
+	}
+	publicWitness, err := fullWitness.Public()
+	if err != nil {
+		return err
+	}
+	proof, err := b.prove(cs, pk, fullWitness)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(*proofPath, proof); err != nil {
+		return err
+	}
+	if err := writeFile(*publicPath, publicWitness); err != nil {
+		return err
+	}
+	if *historyPath == "" {
+		return nil
+	}
+	if _, err := lineage.Append(head.Version, head.Digest, next.Digest, newCiphertext); err != nil {
+		return err
+	}
+	return lineage.Save(*historyPath)
+}
+
+// loadLineage reads the lineage in file name, or starts one from the old profile if name is
+// empty or does not exist
+func loadLineage(name string, oldPath string, key *fr.Element, oldNonce *big.Int) (*history.Store, error) {
+	if name != "" {
+		if _, err := os.Stat(name); err == nil {
+			return history.Load(name, phd.Cipher)
+		}
+	}
+	issued, err := phd.EncryptProfile(oldPath, key, oldNonce)
+	if err != nil {
+		return nil, err
+	}
+	return history.New(phd.Cipher, issued), nil
+}
+
+func showHistory(args []string) error {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	storePath := flags.String("store", "lineage.json", "lineage written by prove -history")
+	flags.Parse(args)
+
+	s, err := history.Load(*storePath, phd.Cipher)
+	if err != nil {
+		return err
+	}
+	for _, e := range s.Entries() {
+		fmt.Printf("version %d: nonce %s, digest %s\n", e.Version, e.Record.Nonce, e.Digest)
+	}
+	fmt.Println("History verified")
+	return nil
+}
+
+// parseNonce reads a decimal or 0x prefixed nonce, or draws one if s is empty
+func parseNonce(s string) (*big.Int, error) {
+	if s == "" {
+		return circuit.NewNonce()
+	}
+	nonce, ok := new(big.Int).SetString(s, 0)
+	if !ok || nonce.Sign() < 0 || nonce.BitLen() > circuit.NonceBits {
+		return nil, fmt.Errorf("invalid nonce %s", s)
+	}
+	return nonce, nil
+}
+
+// parseRoleFiles reads a comma separated list of role=file pairs
+func parseRoleFiles(s string) (map[string]string, error) {
+	res := map[string]string{}
+	if s == "" {
+		return res, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid role=file pair %q", pair)
+		}
+		role, name := parts[0], parts[1]
+		if _, ok := res[role]; ok {
+			return nil, fmt.Errorf("role %s appears twice", role)
+		}
+		res[role] = name
+	}
+	return res, nil
+}
+
+func issuer(args []string) error {
+	flags := flag.NewFlagSet("issuer", flag.ExitOnError)
+	keyPath := flags.String("o", "issuer.key", "output private key")
+	pubPath := flags.String("pub", "issuer.pub", "output public key")
+	flags.Parse(args)
+
+	signer, err := circuit.NewIssuer(ecc.BN254)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*keyPath, signer.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(*pubPath, signer.Public().Bytes(), 0644)
+}
+
+func sign(args []string) error {
+	flags := flag.NewFlagSet("sign", flag.ExitOnError)
+	issuerPath := flags.String("issuer", "issuer.key", "private key of the issuer")
+	profilePath := flags.String("profile", "oldProfile.json", "profile to issue")
+	keyHex := flags.String("key", "", "encryption key of the holder, e.g. 0x52fd...")
+	nonceStr := flags.String("nonce", "", "nonce to encrypt the profile with, random if empty")
+	out := flags.String("o", "old.sig", "output signature")
+	flags.Parse(args)
+
+	if *keyHex == "" {
+		return errors.New("missing -key")
+	}
+	key, err := new(fr.Element).SetString(*keyHex)
+	if err != nil {
+		return err
+	}
+	buf, err := os.ReadFile(*issuerPath)
+	if err != nil {
+		return err
+	}
+	signer := new(eddsa.PrivateKey)
+	if _, err := signer.SetBytes(buf); err != nil {
+		return err
+	}
+	nonce, err := parseNonce(*nonceStr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Nonce: %s\n", nonce)
+	sig, err := phd.SignProfile(signer, *profilePath, key, nonce)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, sig, 0644)
+}
+
+func approve(args []string) error {
+	flags := flag.NewFlagSet("approve", flag.ExitOnError)
+	editorPath := flags.String("editor", "editor.key", "private key of the editor")
+	oldPath := flags.String("old", "oldProfile.json", "profile before the edit")
+	newPath := flags.String("new", "newProfile.json", "profile after the edit")
+	keyHex := flags.String("key", "", "encryption key of the holder, e.g. 0x52fd...")
+	oldNonceStr := flags.String("oldnonce", "", "nonce the old profile was encrypted with")
+	newNonceStr := flags.String("newnonce", "", "nonce to encrypt the new profile with, random if empty")
+	out := flags.String("o", "editor.approval", "output approval")
+	flags.Parse(args)
+
+	if *keyHex == "" {
+		return errors.New("missing -key")
+	}
+	if *oldNonceStr == "" {
+		return errors.New("missing -oldnonce")
+	}
+	key, err := new(fr.Element).SetString(*keyHex)
+	if err != nil {
+		return err
+	}
+	buf, err := os.ReadFile(*editorPath)
+	if err != nil {
+		return err
+	}
+	signer := new(eddsa.PrivateKey)
+	if _, err := signer.SetBytes(buf); err != nil {
+		return err
+	}
+	oldNonce, err := parseNonce(*oldNonceStr)
+	if err != nil {
+		return err
+	}
+	newNonce, err := parseNonce(*newNonceStr)
+	if err != nil {
+		return err
+	}
+	if oldNonce.Cmp(newNonce) == 0 {
+		return errors.New("-newnonce must differ from -oldnonce, the keystream of a nonce is used once")
+	}
+	fmt.Printf("New nonce: %s\n", newNonce)
+	approval, err := phd.ApproveEdit(signer, *oldPath, *newPath, key, oldNonce, newNonce)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, approval, 0644)
+}
+
+func readIssuerPublicKey(name string) (signature.PublicKey, error) {
+	buf, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	res := circuit.NewIssuerPublicKey(ecc.BN254)
+	if _, err := res.SetBytes(buf); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// parseBlinding reads a decimal or 0x prefixed blinding factor, or draws one if s is empty
+func parseBlinding(s string) (*big.Int, error) {
+	if s == "" {
+		return circuit.NewBlinding(ecc.BN254)
+	}
+	blinding, ok := new(big.Int).SetString(s, 0)
+	if !ok || blinding.Sign() < 0 || blinding.Cmp(ecc.BN254.ScalarField()) >= 0 {
+		return nil, fmt.Errorf("invalid blinding factor %s", s)
+	}
+	return blinding, nil
+}
+
+func verify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	backendName := flags.String("backend", "groth16", "proof system, groth16 or plonk")
+	srsPath := flags.String("srs", "", "input KZG SRS, plonk only")
+	vkPath := flags.String("vk", "phd.vk", "input verifying key")
+	proofPath := flags.String("proof", "edit.proof", "input proof")
+	publicPath := flags.String("public", "edit.pub", "input public witness")
+	historyPath := flags.String("history", "", "lineage the edit must start from a version of, e.g. lineage.json")
+	flags.Parse(args)
+
+	b, err := getBackend(*backendName)
+	if err != nil {
+		return err
+	}
+	kzgSRS, err := b.loadSRS(*srsPath)
+	if err != nil {
+		return err
+	}
+	vk := b.newVK(kzgSRS)
+	if err := readFile(*vkPath, vk); err != nil {
+		return err
+	}
+	proof := b.newProof()
+	if err := readFile(*proofPath, proof); err != nil {
+		return err
+	}
+	publicWitness, err := witness.New(ecc.BN254.ScalarField())
+	if err != nil {
+		return err
+	}
+	if err := readFile(*publicPath, publicWitness); err != nil {
+		return err
+	}
+	if err := b.verify(proof, vk, publicWitness); err != nil {
+		return err
+	}
+	if err := checkLineage(*historyPath, []string{*publicPath}, []witness.Witness{publicWitness}); err != nil {
+		return err
+	}
+	fmt.Println("Proof verified")
+	return nil
+}
+
+// phdLayout locates the histories among the public inputs of PhdEditCircuit, which do not
+// depend on the number of publications
+func phdLayout() (aggregate.Layout, error) {
+	edit := phd.InitPhdEditCircuit(1)
+	return aggregate.NewLayout(&edit)
+}
+
+// checkLineage checks that the edit of every public witness, read from the file of the same
+// index in names, starts from a version of the lineage in file historyPath if given. A proof
+// alone shows that the edit extends some history of a record the issuer signed, the lineage
+// ties it to this credential.
+func checkLineage(historyPath string, names []string, publics []witness.Witness) error {
+	if historyPath == "" {
+		return nil
+	}
+	lineage, err := history.Load(historyPath, phd.Cipher)
+	if err != nil {
+		return err
+	}
+	layout, err := phdLayout()
+	if err != nil {
+		return err
+	}
+	for i, public := range publics {
+		old, _, err := layout.Histories(public)
+		if err != nil {
+			return fmt.Errorf("%s: %w", names[i], err)
+		}
+		if !lineage.Contains(old.Version.(*big.Int), old.Digest.(*big.Int), old.Issued.(*big.Int)) {
+			return fmt.Errorf("%s: the edit starts from version %s, which is not in %s", names[i], old.Version, historyPath)
+		}
+	}
+	return nil
+}
+
+func batchVerify(args []string) error {
+	flags := flag.NewFlagSet("batch-verify", flag.ExitOnError)
+	vkPath := flags.String("vk", "phd.vk", "input Groth16 verifying key")
+	proofPaths := flags.String("proofs", "", "comma-separated input proofs")
+	publicPaths := flags.String("publics", "", "comma-separated input public witnesses, in the order of the proofs")
+	historyPath := flags.String("history", "", "lineage every edit must start from a version of, e.g. lineage.json")
+	consecutive := flags.Bool("lineage", false, "the proofs are consecutive edits of one profile, each starting from the history the previous one ended with")
+	flags.Parse(args)
+
+	proofNames, publicNames := strings.Split(*proofPaths, ","), strings.Split(*publicPaths, ",")
+	if *proofPaths == "" || len(proofNames) != len(publicNames) {
+		return errors.New("-proofs and -publics must list as many files")
+	}
+	vk := groth16.NewVerifyingKey(ecc.BN254)
+	if err := readFile(*vkPath, vk); err != nil {
+		return err
+	}
+	proofs := make([]groth16.Proof, len(proofNames))
+	publics := make([]witness.Witness, len(publicNames))
+	for i := range proofNames {
+		proofs[i] = groth16.NewProof(ecc.BN254)
+		if err := readFile(proofNames[i], proofs[i]); err != nil {
+			return err
+		}
+		publicWitness, err := witness.New(ecc.BN254.ScalarField())
+		if err != nil {
+			return err
+		}
+		if err := readFile(publicNames[i], publicWitness); err != nil {
+			return err
+		}
+		publics[i] = publicWitness
+	}
+	var from, to circuit.History
+	var err error
+	if *consecutive {
+		var layout aggregate.Layout
+		if layout, err = phdLayout(); err != nil {
+			return err
+		}
+		from, to, err = aggregate.VerifyLineage(vk, layout, proofs, publics)
+	} else {
+		err = batch.Verify(vk, proofs, publics)
+	}
+	if err != nil {
+		var proofErr *batch.ProofError
+		if errors.As(err, &proofErr) {
+			return fmt.Errorf("%s: %w", proofNames[proofErr.Index], proofErr.Err)
+		}
+		return err
+	}
+	if err := checkLineage(*historyPath, publicNames, publics); err != nil {
+		return err
+	}
+	fmt.Printf("%d proofs verified\n", len(proofs))
+	if *consecutive {
+		fmt.Printf("Lineage from version %s %s to version %s %s\n", from.Version, from.Digest, to.Version, to.Digest)
+	}
+	return nil
+}
+
+func exportSolidity(args []string) error {
+	flags := flag.NewFlagSet("export-solidity", flag.ExitOnError)
+	backendName := flags.String("backend", "groth16", "proof system, groth16 or plonk")
+	srsPath := flags.String("srs", "", "input KZG SRS, plonk only")
+	vkPath := flags.String("vk", "phd.vk", "input verifying key")
+	out := flags.String("o", "phdEditVerifier.sol", "output Solidity file")
+	flags.Parse(args)
+
+	b, err := getBackend(*backendName)
+	if err != nil {
+		return err
+	}
+	kzgSRS, err := b.loadSRS(*srsPath)
+	if err != nil {
+		return err
+	}
+	vk := b.newVK(kzgSRS)
+	if err := readFile(*vkPath, vk); err != nil {
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := vk.(interface{ ExportSolidity(io.Writer) error }).ExportSolidity(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
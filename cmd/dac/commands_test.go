@@ -0,0 +1,136 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/Nullus-Labs/IDEA-DAC/history"
+	"github.com/Nullus-Labs/IDEA-DAC/phd"
+	"github.com/Nullus-Labs/IDEA-DAC/policy"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+)
+
+// TestProveRejectsBadInput checks that prove returns an error, before reading the constraint
+// system, for a malformed profile, an old profile that is not the head of the lineage or a
+// reused nonce
+func TestProveRejectsBadInput(t *testing.T) {
+	const oldPath, newPath = "../phd_profile/oldProfile.json", "../phd_profile/newProfile.json"
+	const keyHex = "0x52fdfc072182654f163f5f0f9a621d729566c74d10037c4d"
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	for _, name := range []string{"issuer", "registrar", "student"} {
+		if err := issuer([]string{"-o", path(name + ".key"), "-pub", path(name + ".pub")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"registrar", "student"} {
+		if err := approve([]string{"-editor", path(name + ".key"), "-old", oldPath, "-new", newPath, "-key", keyHex, "-oldnonce", "1", "-newnonce", "2", "-o", path(name + ".approval")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	key, err := new(fr.Element).SetString(keyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued, err := phd.EncryptProfile(newPath, key, big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := history.New(phd.Cipher, issued).Save(path("lineage.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path("malformed.json"), []byte(`{"status": "Ongoing",`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	proveEdit := func(newProfile string, extra ...string) error {
+		args := []string{
+			"-cs", path("missing.cs"), "-pk", path("missing.pk"), "-policy", "../phd_profile/phdPolicy.json",
+			"-old", oldPath, "-new", newProfile, "-key", keyHex, "-oldnonce", "1", "-newnonce", "2",
+			"-issuer", path("issuer.pub"), "-signature", path("missing.sig"),
+			"-editors", "registrar=" + path("registrar.pub") + ",student=" + path("student.pub"),
+			"-approvals", "registrar=" + path("registrar.approval") + ",student=" + path("student.approval"),
+		}
+		return prove(append(args, extra...))
+	}
+	if err := proveEdit(path("malformed.json")); err == nil || !strings.Contains(err.Error(), "malformed.json") {
+		t.Fatalf("expected the malformed profile to be rejected, got %v", err)
+	}
+	if err := proveEdit(newPath, "-history", path("lineage.json")); err == nil || !strings.Contains(err.Error(), "head") {
+		t.Fatalf("expected an old profile out of the lineage to be rejected, got %v", err)
+	}
+	if err := proveEdit(newPath, "-newnonce", "1"); err == nil || !strings.Contains(err.Error(), "-newnonce") {
+		t.Fatalf("expected a reused nonce to be rejected, got %v", err)
+	}
+	err = approve([]string{"-editor", path("student.key"), "-old", oldPath, "-new", newPath, "-key", keyHex, "-oldnonce", "1", "-newnonce", "1", "-o", path("reused.approval")})
+	if err == nil || !strings.Contains(err.Error(), "-newnonce") {
+		t.Fatalf("expected an approval reusing the nonce to be rejected, got %v", err)
+	}
+}
+
+func TestCheckLineage(t *testing.T) {
+	const oldPath, newPath = "../phd_profile/oldProfile.json", "../phd_profile/newProfile.json"
+	key := new(fr.Element).SetUint64(1234)
+	signer, err := circuit.NewIssuer(ecc.BN254)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := phd.SignProfile(signer, oldPath, key, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	approval, err := phd.ApproveEdit(signer, oldPath, newPath, key, big.NewInt(1), big.NewInt(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued, err := phd.EncryptProfile(oldPath, key, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := phd.EncryptProfile(newPath, key, big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for name, record := range map[string]*circuit.Ciphertext{"lineage.json": issued, "other.json": other} {
+		if err := history.New(phd.Cipher, record).Save(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := policy.Load("../phd_profile/phdPolicy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit, err := phd.LimitFromPolicy(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl := circuit.EmptyACL(phd.MaxEditors, len(circuit.PhdACLPaths))
+	assignment, err := phd.GetAssignment(oldPath, newPath, limit, key, big.NewInt(99), big.NewInt(1), big.NewInt(2), signer.Public(), sig, acl, []phd.Approval{{Editor: 0, Signature: approval}}, nil, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := full.Public()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publics := []witness.Witness{public}
+	if err := checkLineage(filepath.Join(dir, "lineage.json"), []string{"edit.pub"}, publics); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkLineage(filepath.Join(dir, "other.json"), []string{"edit.pub"}, publics); err == nil {
+		t.Fatal("expected an edit starting outside the lineage to be rejected")
+	}
+}
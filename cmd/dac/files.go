@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+func writeFile(name string, v io.WriterTo) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if _, err := v.WriteTo(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readFile(name string, v io.ReaderFrom) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = v.ReadFrom(bufio.NewReader(f))
+	return err
+}
@@ -0,0 +1,71 @@
+// Command dac runs the PhD profile edit circuit step by step, so that the issuer, the holder
+// and the verifier can each run their part on their own machine:
+//
+//	dac compile -maxpub 3 -cs phd.cs
+//	dac setup -cs phd.cs -pk phd.pk -vk phd.vk
+//	dac issuer -o issuer.key -pub issuer.pub
+//	dac sign -issuer issuer.key -profile oldProfile.json -key 0x52fd... -nonce n -o old.sig
+//	dac issuer -o registrar.key -pub registrar.pub
+//	dac approve -editor registrar.key -old oldProfile.json -new newProfile.json -key 0x52fd... -oldnonce n -newnonce m -o registrar.approval
+//	dac prove -maxpub 3 -cs phd.cs -pk phd.pk -policy phdPolicy.json -old oldProfile.json -new newProfile.json -key 0x52fd... -oldnonce n -newnonce m -issuer issuer.pub -signature old.sig -editors registrar=registrar.pub,student=student.pub -approvals registrar=registrar.approval,student=student.approval -history lineage.json -proof edit.proof -public edit.pub
+//	dac verify -vk phd.vk -proof edit.proof -public edit.pub -history lineage.json
+//	dac batch-verify -vk phd.vk -proofs a.proof,b.proof -publics a.pub,b.pub -history lineage.json -lineage
+//	dac history -store lineage.json
+//	dac export-solidity -vk phd.vk -o phdEditVerifier.sol
+//
+// Editors hold the roles of the policy and use the same keys as issuers. Every field of the
+// profile changed by an edit must be allowed to one of the roles approving it. With -history,
+// verify and batch-verify also check that every edit starts from a version of the lineage, and
+// batch-verify -lineage that the proofs are consecutive edits chaining their histories.
+//
+// Every command takes -backend groth16 (the default) or -backend plonk. PLONK replaces the
+// per-circuit setup with a universal KZG SRS, given with -srs to setup, prove, verify and
+// export-solidity; the srs command writes one for testing. batch-verify is Groth16 only.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+var commands = []struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}{
+	{"compile", "compile the edit circuit and write its constraint system", compile},
+	{"srs", "write an insecure KZG SRS large enough for a PLONK constraint system, for testing", srs},
+	{"setup", "run the setup of a constraint system and write the proving and verifying keys", setup},
+	{"issuer", "write a new EdDSA key pair of an issuer or an editor", issuer},
+	{"sign", "encrypt a profile for its holder and sign it as its issuer", sign},
+	{"approve", "sign an edit as an editor", approve},
+	{"prove", "prove an edit and write the proof and its public witness", prove},
+	{"verify", "verify a proof against a verifying key and a public witness", verify},
+	{"batch-verify", "verify Groth16 proofs of the same verifying key at once", batchVerify},
+	{"history", "check and print the lineage of a profile", showHistory},
+	{"export-solidity", "write the Solidity verifier of a verifying key", exportSolidity},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "dac %s: %v\n", c.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dac <command> [flags]")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", c.name, c.usage)
+	}
+	os.Exit(2)
+}
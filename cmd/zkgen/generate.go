@@ -0,0 +1,504 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/Nullus-Labs/IDEA-DAC/dfa"
+)
+
+var ruleKinds = map[string]string{
+	"immutable":     "circuit.RuleImmutable",
+	"appendOnly":    "circuit.RuleAppendOnly",
+	"oneOfSet":      "circuit.RuleOneOfSet",
+	"withinRange":   "circuit.RuleWithinRange",
+	"timeInRange":   "circuit.RuleTimeInRange",
+	"certainFormat": "circuit.RuleCertainFormat",
+	"merkleSet":     "circuit.RuleMerkleSet",
+	"regexFormat":   "circuit.RuleRegexFormat",
+}
+
+type rule struct {
+	path   string
+	kind   string
+	schema *Schema
+}
+
+// merkleProof is a private field of the edit circuit holding the path of a merkleSet rule
+type merkleProof struct {
+	name, path string
+	depth      int
+}
+
+// primitives are the values of x-primitive and the circuit.Primitive they select
+var primitives = map[string]struct {
+	value  circuit.Primitive
+	goExpr string
+}{
+	"mimc":     {circuit.MimcPrimitive, "circuit.MimcPrimitive"},
+	"poseidon": {circuit.PoseidonPrimitive, "circuit.PoseidonPrimitive"},
+}
+
+type generator struct {
+	buf       bytes.Buffer
+	primitive string // x-primitive of the root
+	canonical bool   // x-canonical of the root
+	rules     []rule
+	proofs    []merkleProof
+	regex     bool // some rule needs the dfa package
+	types     map[string]bool
+	inArray   bool // rules can not be bound to the fields of array items
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// Generate returns the Go source of the structs, limit, helpers and edit circuit of the
+// record described by root
+func Generate(root *Schema, pkg string, source string) ([]byte, error) {
+	if root.Type != "object" || root.Title == "" {
+		return nil, fmt.Errorf("the root schema must be an object with a title")
+	}
+	name := root.Title
+	encodedLen, err := root.maxEncodedLen()
+	if err != nil {
+		return nil, err
+	}
+	g := &generator{types: map[string]bool{}, primitive: root.Primitive, canonical: root.Canonical}
+	if g.primitive == "" {
+		g.primitive = "mimc"
+	}
+	if _, ok := primitives[g.primitive]; !ok {
+		return nil, fmt.Errorf("unknown x-primitive %q, expected mimc or poseidon", root.Primitive)
+	}
+	g.printf("// %sMaxRecLen is the number of encrypted blocks of the longest %s record\n", name, name)
+	g.printf("const %sMaxRecLen = %d\n\n", name, (encodedLen+circuit.MergeLen-1)/circuit.MergeLen)
+	if err := g.object(name, root, ""); err != nil {
+		return nil, err
+	}
+	if err := g.limit(name); err != nil {
+		return nil, err
+	}
+	g.editCircuit(name)
+
+	// the imports depend on the rules, which are only known now
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by zkgen from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&src, "package %s\n\n", pkg)
+	src.WriteString("import (\n\tcircuit \"github.com/Nullus-Labs/IDEA-DAC/circuit\"\n")
+	if g.regex {
+		src.WriteString("\t\"github.com/Nullus-Labs/IDEA-DAC/dfa\"\n")
+	}
+	src.WriteString("\t\"github.com/consensys/gnark/frontend\"\n")
+	src.WriteString("\t\"github.com/consensys/gnark/std/signature/eddsa\"\n)\n\n")
+	src.Write(g.buf.Bytes())
+	return format.Source(src.Bytes())
+}
+
+func (g *generator) object(name string, s *Schema, path string) error {
+	if g.types[name] {
+		return fmt.Errorf("type %s is defined twice", name)
+	}
+	g.types[name] = true
+	if len(s.Props) == 0 {
+		return fmt.Errorf("%s has no properties", name)
+	}
+
+	type field struct {
+		name    string
+		json    string
+		goType  string
+		goJSON  string
+		empty   string
+		make    string
+		isArray bool
+		max     int
+		item    string
+		itemMk  string
+	}
+	var fields []field
+	for _, prop := range s.Props {
+		fieldName, err := exported(prop.Name)
+		if err != nil {
+			return err
+		}
+		fieldPath := prop.Name
+		if path != "" {
+			fieldPath = path + "." + prop.Name
+		}
+		if g.inArray && len(prop.Schema.Edit) > 0 {
+			return fmt.Errorf("%s: x-edit is not supported inside array items", prop.Name)
+		}
+		for _, kind := range prop.Schema.Edit {
+			if _, ok := ruleKinds[kind]; !ok {
+				return fmt.Errorf("%s: unknown x-edit %q", fieldPath, kind)
+			}
+			g.rules = append(g.rules, rule{path: fieldPath, kind: kind, schema: prop.Schema})
+		}
+		f := field{name: fieldName, json: prop.Name}
+		switch prop.Schema.Type {
+		case "array":
+			item := prop.Schema.Items
+			if item == nil || prop.Schema.MaxItems <= 0 {
+				return fmt.Errorf("%s: arrays need items and a positive maxItems", fieldPath)
+			}
+			g.inArray = true
+			itemType, itemJSON, itemEmpty, itemMake, err := g.value(fieldName+"Item", item, "")
+			g.inArray = false
+			if err != nil {
+				return fmt.Errorf("%s: %w", fieldPath, err)
+			}
+			if item.Type == "object" {
+				if err := g.isEmpty(itemType, item); err != nil {
+					return err
+				}
+			}
+			f.goType, f.goJSON = "[]"+itemType, "[]"+itemJSON
+			f.isArray, f.max, f.item, f.itemMk = true, prop.Schema.MaxItems, itemEmpty, itemMake
+		default:
+			goType, goJSON, empty, mk, err := g.value(fieldName, prop.Schema, fieldPath)
+			if err != nil {
+				return fmt.Errorf("%s: %w", fieldPath, err)
+			}
+			f.goType, f.goJSON, f.empty, f.make = goType, goJSON, empty, mk
+		}
+		fields = append(fields, f)
+	}
+
+	g.printf("type %s struct {\n", name)
+	for _, f := range fields {
+		g.printf("\t%s %s `json:\"%s\"`\n", f.name, f.goType, f.json)
+	}
+	g.printf("}\n\n")
+	g.printf("type %sJSON struct {\n", name)
+	for _, f := range fields {
+		g.printf("\t%s %s `json:\"%s\"`\n", f.name, f.goJSON, f.json)
+	}
+	g.printf("}\n\n")
+
+	g.printf("func Empty%s() %s {\n\tres := %s{}\n", name, name, name)
+	for _, f := range fields {
+		if f.isArray {
+			g.printf("\tres.%s = make(%s, %d)\n", f.name, f.goType, f.max)
+			g.printf("\tfor i := range res.%s {\n\t\tres.%s[i] = %s\n\t}\n", f.name, f.name, f.item)
+		} else {
+			g.printf("\tres.%s = %s\n", f.name, f.empty)
+		}
+	}
+	g.printf("\treturn res\n}\n\n")
+
+	g.printf("func Make%s(in %sJSON) %s {\n\tres := Empty%s()\n", name, name, name, name)
+	for _, f := range fields {
+		if f.isArray {
+			g.printf("\tif len(in.%s) > %d {\n\t\tpanic(\"too many %s\")\n\t}\n", f.name, f.max, f.json)
+			g.printf("\tfor i := range in.%s {\n\t\tres.%s[i] = %s\n\t}\n", f.name, f.name, fmt.Sprintf(f.itemMk, "in."+f.name+"[i]"))
+		} else {
+			g.printf("\tres.%s = %s\n", f.name, fmt.Sprintf(f.make, "in."+f.name))
+		}
+	}
+	g.printf("\treturn res\n}\n\n")
+	return nil
+}
+
+// value returns the circuit type, JSON type, empty value and Make format string of a scalar
+// or object field, emitting the object types it depends on
+func (g *generator) value(defaultName string, s *Schema, path string) (string, string, string, string, error) {
+	switch s.Type {
+	case "string":
+		capacity, err := s.capacity()
+		if err != nil {
+			return "", "", "", "", err
+		}
+		return "circuit.String", "string", fmt.Sprintf("circuit.EmptyString(%d)", capacity), fmt.Sprintf("circuit.NewString(%%s, %d)", capacity), nil
+	case "integer":
+		maxDigit, err := s.maxDigit()
+		if err != nil {
+			return "", "", "", "", err
+		}
+		if g.canonical && maxDigit > 15 {
+			return "", "", "", "", fmt.Errorf("canonical JSON integers have at most 15 digits, got %d", maxDigit)
+		}
+		return "circuit.Integer", "int64", fmt.Sprintf("circuit.NewInteger(0, %d)", maxDigit), fmt.Sprintf("circuit.NewInteger(%%s, %d)", maxDigit), nil
+	case "object":
+		name := s.Title
+		if name == "" {
+			name = defaultName
+		}
+		if err := g.object(name, s, path); err != nil {
+			return "", "", "", "", err
+		}
+		return name, name + "JSON", "Empty" + name + "()", "Make" + name + "(%s)", nil
+	default:
+		return "", "", "", "", fmt.Errorf("unsupported type %q", s.Type)
+	}
+}
+
+func (g *generator) isEmpty(name string, s *Schema) error {
+	field := s.EmptyField
+	if field == "" {
+		field = s.Props[0].Name
+	}
+	for _, prop := range s.Props {
+		if prop.Name == field {
+			fieldName, _ := exported(prop.Name)
+			g.printf("func (x %s) IsEmpty(api frontend.API) frontend.Variable {\n\treturn x.%s.IsEmpty(api)\n}\n\n", name, fieldName)
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: x-emptyField %q is not a property", name, field)
+}
+
+func (g *generator) limit(name string) error {
+	type limitField struct {
+		name, goType, empty, value, rule string
+	}
+	var fields []limitField
+	for _, r := range g.rules {
+		prefix := goPath(r.path)
+		kind := ruleKinds[r.kind]
+		switch r.kind {
+		case "immutable", "appendOnly":
+			if r.kind == "appendOnly" && r.schema.Type != "array" {
+				return fmt.Errorf("%s: appendOnly applies to arrays only", r.path)
+			}
+			fields = append(fields, limitField{rule: fmt.Sprintf("{Path: %q, Kind: %s}", r.path, kind)})
+		case "oneOfSet":
+			if r.schema.Type != "string" {
+				return fmt.Errorf("%s: oneOfSet applies to strings only", r.path)
+			}
+			size := r.schema.SetSize
+			if size == 0 {
+				size = len(r.schema.Set)
+			}
+			if size == 0 || len(r.schema.Set) > size {
+				return fmt.Errorf("%s: x-set does not fit in x-setSize", r.path)
+			}
+			capacity, _ := r.schema.capacity()
+			var empty, value []string
+			for i := 0; i < size; i++ {
+				empty = append(empty, fmt.Sprintf("circuit.EmptyString(%d)", capacity))
+				if i < len(r.schema.Set) {
+					value = append(value, fmt.Sprintf("circuit.NewString(%q, %d)", r.schema.Set[i], capacity))
+				} else {
+					value = append(value, fmt.Sprintf("circuit.EmptyString(%d)", capacity))
+				}
+			}
+			goType := fmt.Sprintf("[%d]circuit.String", size)
+			fields = append(fields, limitField{
+				name:   prefix + "Set",
+				goType: goType,
+				empty:  fmt.Sprintf("%s{%s}", goType, strings.Join(empty, ", ")),
+				value:  fmt.Sprintf("%s{%s}", goType, strings.Join(value, ", ")),
+				rule:   fmt.Sprintf("{Path: %q, Kind: %s, Set: l.%sSet[:]}", r.path, kind, prefix),
+			})
+		case "withinRange":
+			if r.schema.Type != "integer" || r.schema.Minimum == nil {
+				return fmt.Errorf("%s: withinRange applies to integers with a minimum", r.path)
+			}
+			fields = append(fields, limitField{
+				name:   prefix + "Range",
+				goType: "[2]frontend.Variable",
+				empty:  "[2]frontend.Variable{0, 0}",
+				value:  fmt.Sprintf("[2]frontend.Variable{%d, %d}", *r.schema.Minimum, *r.schema.Maximum),
+				rule:   fmt.Sprintf("{Path: %q, Kind: %s, Range: l.%sRange}", r.path, kind, prefix),
+			})
+		case "timeInRange":
+			if r.schema.Type != "object" || !r.schema.hasInteger("Start") || !r.schema.hasInteger("End") {
+				return fmt.Errorf("%s: timeInRange applies to objects with integer start and end properties", r.path)
+			}
+			fields = append(fields, limitField{
+				name:   prefix + "MinDuration",
+				goType: "frontend.Variable",
+				empty:  "0",
+				value:  fmt.Sprint(r.schema.MinDuration),
+				rule:   fmt.Sprintf("{Path: %q, Kind: %s, MinDuration: l.%sMinDuration}", r.path, kind, prefix),
+			})
+		case "merkleSet":
+			if r.schema.Type != "string" || r.schema.MerkleDepth <= 0 {
+				return fmt.Errorf("%s: merkleSet applies to strings with a positive x-merkleDepth", r.path)
+			}
+			capacity, _ := r.schema.capacity()
+			set, err := circuit.NewMerkleSetWith(primitives[g.primitive].value, r.schema.Set, capacity, r.schema.MerkleDepth)
+			if err != nil {
+				return fmt.Errorf("%s: %w", r.path, err)
+			}
+			root := set.Root()
+			fields = append(fields, limitField{
+				name:   prefix + "MerkleRoot",
+				goType: "frontend.Variable",
+				empty:  "0",
+				value:  fmt.Sprintf("%q", root.String()),
+				rule:   fmt.Sprintf("{Path: %q, Kind: %s, MerkleRoot: l.%sMerkleRoot}", r.path, kind, prefix),
+			})
+			g.proofs = append(g.proofs, merkleProof{name: prefix + "MerkleProof", path: r.path, depth: r.schema.MerkleDepth})
+		case "regexFormat":
+			if r.schema.Type != "string" || r.schema.Pattern == "" {
+				return fmt.Errorf("%s: regexFormat applies to strings with a pattern", r.path)
+			}
+			if _, err := dfa.Compile(r.schema.Pattern); err != nil {
+				return fmt.Errorf("%s: %w", r.path, err)
+			}
+			// the automaton is part of the circuit, not of the public limit
+			regex := unexported(prefix) + "Regex"
+			g.printf("var %s = dfa.MustCompile(%q)\n\n", regex, r.schema.Pattern)
+			g.regex = true
+			fields = append(fields, limitField{rule: fmt.Sprintf("{Path: %q, Kind: %s, Regex: %s}", r.path, kind, regex)})
+		case "certainFormat":
+			codes, err := r.schema.formatCodes()
+			if err != nil {
+				return fmt.Errorf("%s: %w", r.path, err)
+			}
+			if r.schema.Type != "string" || len(codes) == 0 || len(codes) > r.schema.MaxLength {
+				return fmt.Errorf("%s: certainFormat needs an x-format no longer than maxLength", r.path)
+			}
+			goType := fmt.Sprintf("[%d]frontend.Variable", len(codes))
+			empty := strings.TrimSuffix(strings.Repeat("0, ", len(codes)), ", ")
+			value := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(codes)), ", "), "[]")
+			fields = append(fields, limitField{
+				name:   prefix + "Format",
+				goType: goType,
+				empty:  fmt.Sprintf("%s{%s}", goType, empty),
+				value:  fmt.Sprintf("%s{%s}", goType, value),
+				rule:   fmt.Sprintf("{Path: %q, Kind: %s, Format: l.%sFormat[:]}", r.path, kind, prefix),
+			})
+		}
+	}
+
+	g.printf("type %sLimit struct {\n", name)
+	for _, f := range fields {
+		if f.name != "" {
+			g.printf("\t%s %s\n", f.name, f.goType)
+		}
+	}
+	g.printf("}\n\n")
+	g.printf("func (l %sLimit) Rules(api frontend.API) []circuit.Rule {\n\treturn []circuit.Rule{\n", name)
+	for _, f := range fields {
+		g.printf("\t\t%s,\n", f.rule)
+	}
+	g.printf("\t}\n}\n\n")
+	for _, which := range []string{"Empty", "Default"} {
+		if which == "Default" {
+			g.printf("// Default%sLimit returns the bounds declared in the schema\n", name)
+		}
+		g.printf("func %s%sLimit() %sLimit {\n\tres := %sLimit{}\n", which, name, name, name)
+		for _, f := range fields {
+			if f.name == "" {
+				continue
+			}
+			if which == "Empty" {
+				g.printf("\tres.%s = %s\n", f.name, f.empty)
+			} else {
+				g.printf("\tres.%s = %s\n", f.name, f.value)
+			}
+		}
+		g.printf("\treturn res\n}\n\n")
+	}
+	return nil
+}
+
+func (g *generator) editCircuit(name string) {
+	// the Merkle proofs are private, so they are circuit fields next to the public limit
+	var proofFields, proofMap, proofInit strings.Builder
+	limit := "c.Limit"
+	for _, p := range g.proofs {
+		fmt.Fprintf(&proofFields, "\t%s circuit.MerkleProof\n", p.name)
+		fmt.Fprintf(&proofMap, "%q: c.%s, ", p.path, p.name)
+		fmt.Fprintf(&proofInit, "\tres.%s = circuit.NewMerkleProof(%d)\n", p.name, p.depth)
+	}
+	if len(g.proofs) > 0 {
+		limit = fmt.Sprintf("circuit.WithMerkleProofs(c.Limit, map[string]circuit.MerkleProof{%s})", strings.TrimSuffix(proofMap.String(), ", "))
+	}
+	// MiMC and the key order of the struct are the defaults of the circuit package
+	options := ""
+	if g.primitive != "mimc" {
+		options = fmt.Sprintf("\tapi = circuit.WithPrimitive(api, %s)\n", primitives[g.primitive].goExpr)
+	}
+	if g.canonical {
+		options += "\tapi = circuit.WithCanonicalJSON(api)\n"
+	}
+	g.printf(`type %[1]sEditCircuit struct {
+	OldRecord    circuit.Record `+"`gnark:\",public\"`"+`
+	NewRecord    circuit.Record `+"`gnark:\",public\"`"+`
+	IssuerKey    eddsa.PublicKey `+"`gnark:\",public\"`"+`
+	Signature    eddsa.Signature
+	OldHistory   circuit.History `+"`gnark:\",public\"`"+`
+	NewHistory   circuit.History `+"`gnark:\",public\"`"+`
+	PrevHistory  frontend.Variable
+	Limit        %[1]sLimit `+"`gnark:\",public\"`"+`
+	CommittedKey frontend.Variable   `+"`gnark:\",public\"`"+`
+	OldContent   %[1]s
+	NewContent   %[1]s
+	Key          frontend.Variable
+	Blinding     frontend.Variable
+%[2]s}
+
+func (c *%[1]sEditCircuit) Define(api frontend.API) error {
+%[5]s	circuit.EditCheck(api, c.OldRecord, c.NewRecord, c.OldHistory, c.IssuerKey, c.Signature, %[3]s, c.CommittedKey, c.OldContent, c.NewContent, c.Key, c.Blinding)
+	circuit.CheckHistory(api, c.OldHistory, c.NewHistory, c.PrevHistory, c.OldRecord, c.NewRecord)
+	return nil
+}
+
+func Init%[1]sEditCircuit() %[1]sEditCircuit {
+	res := %[1]sEditCircuit{}
+	res.OldContent = Empty%[1]s()
+	res.NewContent = Empty%[1]s()
+	res.Limit = Empty%[1]sLimit()
+	res.Key = 0
+	res.CommittedKey = 0
+	res.Blinding = 0
+	res.IssuerKey = circuit.EmptyIssuerKey()
+	res.Signature = circuit.EmptySignature()
+	res.OldHistory = circuit.EmptyHistory()
+	res.NewHistory = circuit.EmptyHistory()
+	res.PrevHistory = 0
+%[4]s	res.OldRecord = circuit.EmptyRecord(%[1]sMaxRecLen)
+	res.NewRecord = circuit.EmptyRecord(%[1]sMaxRecLen)
+	return res
+}
+`, name, proofFields.String(), limit, proofInit.String(), options)
+}
+
+// exported returns the Go field name of a property, its first letter in upper case. The circuit
+// encodes the field under the property name given by its json tag.
+func exported(name string) (string, error) {
+	for i, c := range name {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return "", fmt.Errorf("property %q is not a Go identifier", name)
+		}
+	}
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		return "", fmt.Errorf("property %q must start with a letter to be exported", name)
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes), nil
+}
+
+// goPath joins the Go field names of the properties of a path, e.g. ProgramYear for programYear
+// and LatestVaccineDosage for LatestVaccine.Dosage
+func goPath(path string) string {
+	var res strings.Builder
+	for _, name := range strings.Split(path, ".") {
+		field, _ := exported(name)
+		res.WriteString(field)
+	}
+	return res.String()
+}
+
+// unexported lowers the leading capitals of an identifier, e.g. ORCID to orcid and StudentID
+// to studentID
+func unexported(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) || (i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
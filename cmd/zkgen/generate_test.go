@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nullus-Labs/IDEA-DAC/circuit"
+)
+
+func TestGeneratePhdProfile(t *testing.T) {
+	data, err := os.ReadFile("../phd_profile/phdProfile.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root Schema
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate(&root, "phd", "phdProfile.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(src)
+	// properties keep the schema order, the key order of a record that is not canonical
+	if strings.Index(code, "\tStatus ") > strings.Index(code, "\tDuration ") {
+		t.Fatal("properties are out of order")
+	}
+	for _, want := range []string{
+		"const PhDProfileMaxRecLen = 69",
+		"res.Status = circuit.NewString(in.Status, 77)",
+		"res.StudentID = circuit.NewString(in.StudentID, 6)",
+		"StatusSet           [4]circuit.String",
+		`{Path: "duration", Kind: circuit.RuleTimeInRange, MinDuration: l.DurationMinDuration}`,
+		"func (x Publication) IsEmpty(api frontend.API) frontend.Variable",
+		"api = circuit.WithCanonicalJSON(api)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code lacks %q", want)
+		}
+	}
+}
+
+// TestGeneratedPhdVerdicts builds the code generated from the PhD schema in a module of its
+// own, next to testdata/phd which checks that it accepts and rejects the same edits as package
+// phd
+func TestGeneratedPhdVerdicts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("solves the PhD edit circuits")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go tool is not installed")
+	}
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("../phd_profile/phdProfile.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate(&schema, "gen", "phdProfile.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	goMod := fmt.Sprintf("module zkgentest\n\ngo 1.16\n\nrequire github.com/Nullus-Labs/IDEA-DAC v0.0.0\n\nreplace github.com/Nullus-Labs/IDEA-DAC => %s\n", root)
+	files := map[string][]byte{"go.mod": []byte(goMod), "profile_gen.go": src}
+	for name, from := range map[string]string{
+		"go.sum":          "../../go.sum",
+		"verdict_test.go": "testdata/phd/verdict_test.go",
+		"oldProfile.json": "../phd_profile/oldProfile.json",
+		"newProfile.json": "../phd_profile/newProfile.json",
+		"phdPolicy.json":  "../phd_profile/phdPolicy.json",
+	} {
+		if files[name], err = os.ReadFile(from); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(goTool, "test", "-run", "TestSameVerdicts", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
+
+// worstCase returns the longest value of s, each character of its strings being escaped in 6
+// bytes
+func worstCase(s *Schema, char string) interface{} {
+	switch s.Type {
+	case "string":
+		return strings.Repeat(char, s.MaxLength)
+	case "integer":
+		return *s.Maximum
+	case "array":
+		res := make([]interface{}, s.MaxItems)
+		for i := range res {
+			res[i] = worstCase(s.Items, char)
+		}
+		return res
+	default:
+		res := map[string]interface{}{}
+		for _, prop := range s.Props {
+			res[prop.Name] = worstCase(prop.Schema, char)
+		}
+		return res
+	}
+}
+
+func TestMaxEncodedLen(t *testing.T) {
+	data, err := os.ReadFile("../phd_profile/phdProfile.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root Schema
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatal(err)
+	}
+	max, err := root.maxEncodedLen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// escaped by encoding/json, or by both as a control character
+	for _, char := range []string{"<", "\u2028", "\x1f"} {
+		record, err := json.Marshal(worstCase(&root, char))
+		if err != nil {
+			t.Fatal(err)
+		}
+		canonical, err := circuit.CanonicalJSON(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(record) > max || len(canonical) > max {
+			t.Errorf("%q: records of %d and %d bytes exceed %d", char, len(record), len(canonical), max)
+		}
+	}
+
+	bytes := Schema{Type: "string", MaxLength: 5, MaxBytes: 21}
+	if _, err := bytes.capacity(); err == nil {
+		t.Fatal("expected x-maxBytes above 4*maxLength to be rejected")
+	}
+}
+
+func TestGenerateRejectsRuleInArrayItem(t *testing.T) {
+	schema := `{"title": "R", "type": "object", "properties": {
+		"Items": {"type": "array", "maxItems": 2, "items": {"type": "object", "properties": {
+			"Name": {"type": "string", "maxLength": 4, "x-edit": "immutable"}}}}}}`
+	var root Schema
+	if err := json.Unmarshal([]byte(schema), &root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Generate(&root, "r", "r.json"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestGenerateRejectsTimeInRangeWithoutIntegers(t *testing.T) {
+	for _, props := range []string{
+		`"start": {"type": "integer", "maximum": 99}`,
+		`"start": {"type": "integer", "maximum": 99}, "end": {"type": "string", "maxLength": 2}`,
+		`"begin": {"type": "integer", "maximum": 99}, "end": {"type": "integer", "maximum": 99}`,
+	} {
+		schema := `{"title": "R", "type": "object", "properties": {
+			"Duration": {"type": "object", "x-edit": "timeInRange", "properties": {` + props + `}}}}`
+		var root Schema
+		if err := json.Unmarshal([]byte(schema), &root); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Generate(&root, "r", "r.json"); err == nil {
+			t.Errorf("%s: expected an error", props)
+		}
+	}
+}
+
+func TestGenerateMerkleSet(t *testing.T) {
+	schema := `{"title": "Enrollment", "type": "object", "properties": {
+		"Course": {"type": "string", "maxLength": 8, "x-edit": "merkleSet", "x-merkleDepth": 12,
+			"x-set": ["CS101", "CS102", "MA201"]}}}`
+	var root Schema
+	if err := json.Unmarshal([]byte(schema), &root); err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate(&root, "enrollment", "enrollment.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(src)
+	for _, want := range []string{
+		"CourseMerkleRoot frontend.Variable",
+		`{Path: "Course", Kind: circuit.RuleMerkleSet, MerkleRoot: l.CourseMerkleRoot}`,
+		"CourseMerkleProof circuit.MerkleProof",
+		`circuit.WithMerkleProofs(c.Limit, map[string]circuit.MerkleProof{"Course": c.CourseMerkleProof})`,
+		"res.CourseMerkleProof = circuit.NewMerkleProof(12)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code lacks %q", want)
+		}
+	}
+}
+
+func TestGenerateRegexFormat(t *testing.T) {
+	schema := `{"title": "Researcher", "type": "object", "properties": {
+		"ORCID": {"type": "string", "maxLength": 19, "x-edit": "regexFormat",
+			"pattern": "\\d{4}-\\d{4}-\\d{4}-\\d{3}[\\dX]"}}}`
+	var root Schema
+	if err := json.Unmarshal([]byte(schema), &root); err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate(&root, "researcher", "researcher.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(src)
+	for _, want := range []string{
+		`"github.com/Nullus-Labs/IDEA-DAC/dfa"`,
+		`var orcidRegex = dfa.MustCompile("\\d{4}-\\d{4}-\\d{4}-\\d{3}[\\dX]")`,
+		`{Path: "ORCID", Kind: circuit.RuleRegexFormat, Regex: orcidRegex}`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code lacks %q", want)
+		}
+	}
+}
+
+func TestGeneratePrimitive(t *testing.T) {
+	schema := `{"title": "Enrollment", "type": "object", "x-primitive": "poseidon", "properties": {
+		"Course": {"type": "string", "maxLength": 8, "x-edit": "merkleSet", "x-merkleDepth": 4,
+			"x-set": ["CS101", "CS102", "MA201"]}}}`
+	var root Schema
+	if err := json.Unmarshal([]byte(schema), &root); err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate(&root, "enrollment", "enrollment.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := circuit.NewMerkleSetWith(circuit.PoseidonPrimitive, []string{"CS101", "CS102", "MA201"}, 33, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merkleRoot := set.Root()
+	code := string(src)
+	for _, want := range []string{
+		"api = circuit.WithPrimitive(api, circuit.PoseidonPrimitive)",
+		fmt.Sprintf("res.CourseMerkleRoot = %q", merkleRoot.String()),
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code lacks %q", want)
+		}
+	}
+
+	root.Primitive = "sha256"
+	if _, err := Generate(&root, "enrollment", "enrollment.json"); err == nil {
+		t.Fatal("expected an unknown primitive to be rejected")
+	}
+}
@@ -0,0 +1,72 @@
+// Command zkgen generates the circuit structs, limit, Make/Empty helpers and edit circuit of a
+// credential from its JSON Schema.
+//
+//	go run ./cmd/zkgen -schema cmd/phd_profile/phdProfile.schema.json -pkg phd -o phd/profile_gen.go
+//
+// Besides type, properties, items, maxLength, maxItems, minimum, maximum and pattern, the
+// schema uses:
+//   - x-edit: the edit bounds of a field, any of immutable, appendOnly, oneOfSet, withinRange,
+//     timeInRange, certainFormat, merkleSet and regexFormat
+//   - x-set, x-setSize: the default set and the set capacity of oneOfSet, x-set is also the
+//     default set of merkleSet
+//   - x-merkleDepth: the depth of the merkleSet tree, the set may hold up to 2^depth items
+//   - x-format: the format of certainFormat, one character per position, A: capital letter,
+//     a: small letter, 9: number, #: special character
+//   - x-minDuration: the default minimum duration of timeInRange in seconds
+//   - x-maxBytes: the UTF-8 bytes a string may take, 4*maxLength by default, e.g. maxLength
+//     for an ASCII identifier
+//   - x-emptyField: the field telling whether an array item is empty, the first one by default
+//   - x-primitive: at the root, the hash and cipher of the circuit, mimc (the default) or
+//     poseidon, which only runs on BN254
+//   - x-canonical: at the root, true to encode records in canonical JSON (RFC 8785), keys
+//     sorted, as circuit.CanonicalJSON writes them
+//
+// The pattern of regexFormat must match the whole string, unlike in JSON Schema where it may
+// match a substring. String capacities come from maxLength, counted in characters, or
+// x-maxBytes, integer digits from maximum and array capacities from maxItems; the record
+// capacity is derived from them, each character being escaped in at most 6 bytes.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	schemaPath := flag.String("schema", "", "JSON Schema of the credential")
+	pkg := flag.String("pkg", "main", "package of the generated file")
+	out := flag.String("o", "", "output file, stdout if empty")
+	flag.Parse()
+	if *schemaPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(*schemaPath)
+	if err != nil {
+		fail(err)
+	}
+	var root Schema
+	if err := json.Unmarshal(data, &root); err != nil {
+		fail(err)
+	}
+	src, err := Generate(&root, *pkg, filepath.Base(*schemaPath))
+	if err != nil {
+		fail(err)
+	}
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "zkgen:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Schema is the subset of JSON Schema understood by zkgen, extended with the x- keywords
+// describing circuit capacities and edit bounds
+type Schema struct {
+	Title     string          `json:"title"`
+	Type      string          `json:"type"`
+	MaxLength int             `json:"maxLength"` // in characters
+	Pattern   string          `json:"pattern"`   // regexFormat, matched against the whole string
+	Minimum   *int64          `json:"minimum"`
+	Maximum   *int64          `json:"maximum"`
+	MaxItems  int             `json:"maxItems"`
+	Items     *Schema         `json:"items"`
+	RawProps  json.RawMessage `json:"properties"`
+	Props     []Property      `json:"-"`
+
+	Edit        EditList `json:"x-edit"`        // edit bounds of the field, see ruleKinds
+	MaxBytes    int      `json:"x-maxBytes"`    // UTF-8 bytes of a string, defaults to 4*maxLength
+	Set         []string `json:"x-set"`         // default set of oneOfSet and merkleSet
+	MerkleDepth int      `json:"x-merkleDepth"` // depth of the merkleSet tree, which holds up to 2^depth items
+	SetSize     int      `json:"x-setSize"`     // capacity of the oneOfSet set, defaults to len(x-set)
+	Format      string   `json:"x-format"`      // format of certainFormat, A: capital, a: small, 9: number, #: special
+	MinDuration int64    `json:"x-minDuration"` // default minimum duration of timeInRange, in seconds
+	EmptyField  string   `json:"x-emptyField"`  // field telling whether an array item is empty, defaults to the first one
+	Primitive   string   `json:"x-primitive"`   // hash and cipher of the circuit, mimc or poseidon, root only
+	Canonical   bool     `json:"x-canonical"`   // records in canonical JSON, see circuit.WithCanonicalJSON, root only
+}
+
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// EditList accepts both "x-edit": "appendOnly" and "x-edit": ["certainFormat", "immutable"]
+type EditList []string
+
+func (e *EditList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*e = EditList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("x-edit must be a string or an array of strings")
+	}
+	*e = many
+	return nil
+}
+
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type plain Schema
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	if len(s.RawProps) == 0 {
+		return nil
+	}
+	// The order of the properties is the order of the keys in the encoded record,
+	// so they are decoded one by one instead of into a map
+	dec := json.NewDecoder(bytes.NewReader(s.RawProps))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return errors.New("properties must be an object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		prop := Property{Name: tok.(string), Schema: new(Schema)}
+		if err := dec.Decode(prop.Schema); err != nil {
+			return fmt.Errorf("property %s: %w", prop.Name, err)
+		}
+		s.Props = append(s.Props, prop)
+	}
+	return nil
+}
+
+// maxDigit is the number of decimal digits of the largest value the integer can take
+func (s *Schema) maxDigit() (int, error) {
+	if s.Maximum == nil || *s.Maximum < 0 {
+		return 0, errors.New("integers need a non-negative maximum")
+	}
+	return len(fmt.Sprint(*s.Maximum)), nil
+}
+
+// capacity is the length of the String holding the field, including the leading length. A
+// String holds UTF-8 bytes, up to 4 per character of maxLength unless x-maxBytes is tighter.
+func (s *Schema) capacity() (int, error) {
+	if s.MaxLength <= 0 {
+		return 0, errors.New("strings need a positive maxLength")
+	}
+	if s.MaxBytes == 0 {
+		return 4*s.MaxLength + 1, nil
+	}
+	if s.MaxBytes < s.MaxLength || s.MaxBytes > 4*s.MaxLength {
+		return 0, fmt.Errorf("x-maxBytes must be between maxLength and %d", 4*s.MaxLength)
+	}
+	return s.MaxBytes + 1, nil
+}
+
+// hasInteger tells whether the object has an integer property of Go field name, e.g. Start for
+// start
+func (s *Schema) hasInteger(name string) bool {
+	for _, prop := range s.Props {
+		if field, _ := exported(prop.Name); field == name && prop.Schema.Type == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) formatCodes() ([]int, error) {
+	codes := make([]int, len(s.Format))
+	for i, c := range s.Format {
+		switch c {
+		case 'A':
+			codes[i] = 1
+		case 'a':
+			codes[i] = 2
+		case '9':
+			codes[i] = 3
+		case '#':
+			codes[i] = 4
+		default:
+			return nil, fmt.Errorf("invalid x-format character %q", c)
+		}
+	}
+	return codes, nil
+}
+
+// maxEncodedLen is the length of the longest compact JSON the schema allows
+func (s *Schema) maxEncodedLen() (int, error) {
+	switch s.Type {
+	case "string":
+		// a character is escaped in at most 6 bytes, e.g. \u001f or \u2028
+		if _, err := s.capacity(); err != nil {
+			return 0, err
+		}
+		return 6*s.MaxLength + 2, nil
+	case "integer":
+		return s.maxDigit()
+	case "array":
+		item, err := s.Items.maxEncodedLen()
+		if err != nil {
+			return 0, err
+		}
+		if s.MaxItems == 0 {
+			return 2, nil
+		}
+		return 2 + s.MaxItems*item + s.MaxItems - 1, nil
+	case "object":
+		if len(s.Props) == 0 {
+			return 2, nil
+		}
+		total := 2 + len(s.Props) - 1
+		for _, prop := range s.Props {
+			value, err := prop.Schema.maxEncodedLen()
+			if err != nil {
+				return 0, err
+			}
+			total += len(prop.Name) + 3 + value
+		}
+		return total, nil
+	default:
+		return 0, fmt.Errorf("unsupported type %q", s.Type)
+	}
+}
@@ -0,0 +1,152 @@
+package gen
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/Nullus-Labs/IDEA-DAC/history"
+	"github.com/Nullus-Labs/IDEA-DAC/phd"
+	"github.com/Nullus-Labs/IDEA-DAC/policy"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/signature"
+	"github.com/consensys/gnark/test"
+)
+
+// This file is copied next to the code zkgen generates from phdProfile.schema.json, see
+// TestGeneratedPhdVerdicts, with the profiles and the policy of cmd/phd_profile.
+
+// assign returns the witness of the generated circuit for the edit from oldProfile to
+// newProfile, the old profile being the one issued by issuer
+func assign(t *testing.T, oldProfile, newProfile PhDProfileJSON, key *big.Int, blinding *big.Int, oldNonce *big.Int, newNonce *big.Int, issuer signature.Signer) PhDProfileEditCircuit {
+	cipher := circuit.Cipher{Curve: ecc.BN254, Primitive: circuit.MimcPrimitive}
+	encrypt := func(profile PhDProfileJSON, nonce *big.Int) *circuit.Ciphertext {
+		data, err := json.Marshal(profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		canonical, err := circuit.CanonicalJSON(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := cipher.Encrypt(canonical, key, nonce, PhDProfileMaxRecLen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	oldRec, newRec := encrypt(oldProfile, oldNonce), encrypt(newProfile, newNonce)
+	sig, err := cipher.Sign(issuer, oldRec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := InitPhDProfileEditCircuit()
+	res.OldContent = MakePhDProfile(oldProfile)
+	res.NewContent = MakePhDProfile(newProfile)
+	res.Limit = DefaultPhDProfileLimit()
+	res.Key = key
+	res.Blinding = blinding
+	res.CommittedKey = circuit.CommitKey(ecc.BN254, key, blinding)
+	res.OldRecord = oldRec.Record()
+	res.NewRecord = newRec.Record()
+	lineage := history.New(cipher, oldRec)
+	res.OldHistory = lineage.Head().History()
+	res.NewHistory = lineage.Next(newRec).History()
+	res.PrevHistory = lineage.Prev()
+	res.IssuerKey.Assign(circuit.IssuerCurve(ecc.BN254), issuer.Public().Bytes())
+	res.Signature.Assign(circuit.IssuerCurve(ecc.BN254), sig)
+	return res
+}
+
+func readProfile(t *testing.T, name string) PhDProfileJSON {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res PhDProfileJSON
+	if err := json.Unmarshal(data, &res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// TestSameVerdicts checks that the generated edit circuit accepts and rejects the edits
+// phd.PhdEditCircuit does, both approved by every editor of the policy
+func TestSameVerdicts(t *testing.T) {
+	f, err := policy.Load("phdPolicy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit, err := phd.LimitFromPolicy(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := new(fr.Element).SetUint64(1234)
+	blinding, oldNonce, newNonce := big.NewInt(99), big.NewInt(1), big.NewInt(2)
+	signers := make([]signature.Signer, 3)
+	for i := range signers {
+		if signers[i], err = circuit.NewIssuer(ecc.BN254); err != nil {
+			t.Fatal(err)
+		}
+	}
+	issuer, registrar, student := signers[0], signers[1], signers[2]
+	acl, err := phd.ACLFromPolicy(f, map[string]signature.PublicKey{"registrar": registrar.Public(), "student": student.Public()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := phd.SignProfile(issuer, "oldProfile.json", key, oldNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := readProfile(t, "oldProfile.json")
+	for _, tc := range []struct {
+		name  string
+		edit  func(p *PhDProfileJSON)
+		valid bool
+	}{
+		{"valid", func(p *PhDProfileJSON) {}, true},
+		{"status outside the set", func(p *PhDProfileJSON) { p.Status = "Paused" }, false},
+		{"studentID changed", func(p *PhDProfileJSON) { p.StudentID = "UNI43" }, false},
+		{"publication modified", func(p *PhDProfileJSON) { p.Publications[0].Title = "ZK-Proof" }, false},
+		{"duration too short", func(p *PhDProfileJSON) { p.Duration.End = p.Duration.Start + 1000 }, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			edited := readProfile(t, "newProfile.json")
+			tc.edit(&edited)
+			data, err := json.Marshal(edited)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile("edited.json", data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			var approvals []phd.Approval
+			for _, editor := range []signature.Signer{registrar, student} {
+				approval, err := phd.ApproveEdit(editor, "oldProfile.json", "edited.json", key, oldNonce, newNonce)
+				if err != nil {
+					t.Fatal(err)
+				}
+				approvals = append(approvals, phd.Approval{Editor: len(approvals), Signature: approval})
+			}
+			phdCircuit := phd.InitPhdEditCircuit(3)
+			phdAssignment, err := phd.GetAssignment("oldProfile.json", "edited.json", limit, key, blinding, oldNonce, newNonce, issuer.Public(), sig, acl, approvals, nil, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			phdErr := test.IsSolved(&phdCircuit, &phdAssignment, ecc.BN254.ScalarField())
+
+			genCircuit := InitPhDProfileEditCircuit()
+			genAssignment := assign(t, old, edited, key.BigInt(new(big.Int)), blinding, oldNonce, newNonce, issuer)
+			genErr := test.IsSolved(&genCircuit, &genAssignment, ecc.BN254.ScalarField())
+
+			if (phdErr == nil) != tc.valid || (genErr == nil) != tc.valid {
+				t.Fatalf("expected valid %v, got %v from phd and %v from the generated circuit", tc.valid, phdErr, genErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Package dfa compiles regular expressions into deterministic automata over ASCII characters,
+// which the circuit package runs over a String one character at a time.
+package dfa
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"sort"
+)
+
+// Alphabet is the number of characters the automaton reads, any other character is rejected
+const Alphabet = 128
+
+// DFA is a deterministic automaton matching whole strings. State 0 is the dead state: it
+// rejects and never leaves.
+type DFA struct {
+	Pattern string
+	Start   int
+	Accept  []bool
+	Next    [][Alphabet]int // Next[state][character]
+}
+
+// Compile builds the automaton of pattern, in the syntax of package regexp. The pattern must
+// match the whole string, as if it were written ^(?:pattern)$. Word boundaries and multi-line
+// assertions are not supported.
+func Compile(pattern string) (*DFA, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	for _, inst := range prog.Inst {
+		if inst.Op == syntax.InstEmptyWidth && syntax.EmptyOp(inst.Arg)&^(syntax.EmptyBeginText|syntax.EmptyEndText) != 0 {
+			return nil, fmt.Errorf("unsupported assertion in %q", pattern)
+		}
+	}
+
+	c := compiler{prog: prog, index: map[string]int{}}
+	res := &DFA{Pattern: pattern, Start: 1}
+	// the dead state, then the start state, which is never shared since ^ only holds there
+	res.Accept = append(res.Accept, false)
+	res.Next = append(res.Next, [Alphabet]int{})
+	start := c.closure([]uint32{uint32(prog.Start)}, syntax.EmptyBeginText)
+	c.states = append(c.states, nil, start)
+	res.Accept = append(res.Accept, c.accepts(start, syntax.EmptyBeginText|syntax.EmptyEndText))
+	res.Next = append(res.Next, [Alphabet]int{})
+	for q := 1; q < len(c.states); q++ {
+		for a := 0; a < Alphabet; a++ {
+			var out []uint32
+			for _, pc := range c.states[q] {
+				inst := &prog.Inst[pc]
+				switch inst.Op {
+				case syntax.InstRune, syntax.InstRune1, syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+					if inst.MatchRune(rune(a)) {
+						out = append(out, inst.Out)
+					}
+				}
+			}
+			if len(out) == 0 {
+				continue
+			}
+			next := c.closure(out, 0)
+			key := fmt.Sprint(next)
+			r, ok := c.index[key]
+			if !ok {
+				r = len(c.states)
+				c.index[key] = r
+				c.states = append(c.states, next)
+				res.Accept = append(res.Accept, c.accepts(next, syntax.EmptyEndText))
+				res.Next = append(res.Next, [Alphabet]int{})
+			}
+			res.Next[q][a] = r
+		}
+	}
+	return res, nil
+}
+
+// MustCompile is like Compile but panics if the pattern is not supported
+func MustCompile(pattern string) *DFA {
+	res, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// Match runs the automaton over s
+func (d *DFA) Match(s string) bool {
+	state := d.Start
+	for _, c := range s {
+		if c < 0 || c >= Alphabet {
+			return false
+		}
+		state = d.Next[state][c]
+	}
+	return d.Accept[state]
+}
+
+func (d *DFA) String() string {
+	return d.Pattern
+}
+
+type compiler struct {
+	prog   *syntax.Prog
+	states [][]uint32 // instructions of each state, see closure
+	index  map[string]int
+}
+
+// closure returns the sorted instructions reachable from pcs without reading a character,
+// following the assertions in flags. Assertions that do not hold are kept, so that accepts
+// can follow the end of text ones later.
+func (c *compiler) closure(pcs []uint32, flags syntax.EmptyOp) []uint32 {
+	seen := map[uint32]bool{}
+	var res []uint32
+	var visit func(pc uint32)
+	visit = func(pc uint32) {
+		if seen[pc] {
+			return
+		}
+		seen[pc] = true
+		inst := &c.prog.Inst[pc]
+		switch inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			visit(inst.Out)
+			visit(inst.Arg)
+		case syntax.InstCapture, syntax.InstNop:
+			visit(inst.Out)
+		case syntax.InstEmptyWidth:
+			if syntax.EmptyOp(inst.Arg)&^flags == 0 {
+				visit(inst.Out)
+			} else {
+				res = append(res, pc)
+			}
+		case syntax.InstFail:
+		default:
+			res = append(res, pc)
+		}
+	}
+	for _, pc := range pcs {
+		visit(pc)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+func (c *compiler) accepts(pcs []uint32, flags syntax.EmptyOp) bool {
+	for _, pc := range c.closure(pcs, flags) {
+		if c.prog.Inst[pc].Op == syntax.InstMatch {
+			return true
+		}
+	}
+	return false
+}
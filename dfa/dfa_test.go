@@ -0,0 +1,42 @@
+package dfa
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestMatchLikeRegexp(t *testing.T) {
+	patterns := []string{
+		`[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`,
+		`\d{4}-\d{4}-\d{4}-\d{3}[\dX]`,
+		`\d{4}-(0[1-9]|1[0-2])-(0[1-9]|[12]\d|3[01])`,
+		`(PHD-)?[A-Z]{3}\d{2}`,
+		`^a*$|b`,
+		`x?`,
+	}
+	inputs := []string{
+		"", "a", "aa", "b", "ab", "x", "xx",
+		"alice@uni.edu", "alice@uni", "Alice@uni.edu",
+		"0000-0002-1825-0097", "0000-0002-1825-009X", "0000-0002-1825-009",
+		"2023-02-28", "2023-13-01", "2023-1-01",
+		"UNI42", "PHD-UNI42", "PHD-UNI4", "phd-UNI42", "é",
+	}
+	for _, pattern := range patterns {
+		d, err := Compile(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		re := regexp.MustCompile(`^(?:` + pattern + `)$`)
+		for _, s := range inputs {
+			if d.Match(s) != re.MatchString(s) {
+				t.Errorf("%q on %q: got %v", pattern, s, d.Match(s))
+			}
+		}
+	}
+}
+
+func TestCompileRejectsWordBoundary(t *testing.T) {
+	if _, err := Compile(`\bword`); err == nil {
+		t.Fatal("expected an error")
+	}
+}
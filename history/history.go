@@ -0,0 +1,167 @@
+// Package history keeps the lineage of a credential: every version of its encrypted record
+// chained by the history digest the edit circuits check with circuit.CheckHistory. An auditor
+// replays the chain to check that each proven edit extends the latest version, so an old edit
+// cannot be replayed and the history cannot fork.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+)
+
+// Entry is a version of the credential, the history digest up to it and the digest of the
+// issued record, the one the issuer signed
+type Entry struct {
+	Version uint64              `json:"version"`
+	Record  *circuit.Ciphertext `json:"record"`
+	Digest  *big.Int            `json:"digest"`
+	Issued  *big.Int            `json:"issued"`
+}
+
+// History returns the entry as the assignment of a circuit.History
+func (e Entry) History() circuit.History {
+	return circuit.History{Version: e.Version, Digest: e.Digest, Issued: e.Issued}
+}
+
+// Store is the lineage of a credential, from the record issued at version 0
+type Store struct {
+	cipher  circuit.Cipher
+	entries []Entry
+}
+
+// New starts the lineage of the record issued with cipher
+func New(cipher circuit.Cipher, issued *circuit.Ciphertext) *Store {
+	e := Entry{Version: 0, Record: issued, Digest: cipher.ChainHistory(big.NewInt(0), issued), Issued: cipher.RecordDigest(issued.Nonce, issued.Blocks)}
+	return &Store{cipher: cipher, entries: []Entry{e}}
+}
+
+// Head is the latest version
+func (s *Store) Head() Entry {
+	return s.entries[len(s.entries)-1]
+}
+
+// Prev is the history digest before the head, the one the next edit opens the head with
+func (s *Store) Prev() *big.Int {
+	if len(s.entries) == 1 {
+		return big.NewInt(0)
+	}
+	return s.entries[len(s.entries)-2].Digest
+}
+
+// Entries returns the lineage, oldest first
+func (s *Store) Entries() []Entry {
+	return append([]Entry(nil), s.entries...)
+}
+
+// IsHead tells whether record is the latest version
+func (s *Store) IsHead(record *circuit.Ciphertext) bool {
+	head := s.Head().Record
+	return s.cipher.RecordDigest(record.Nonce, record.Blocks).Cmp(s.cipher.RecordDigest(head.Nonce, head.Blocks)) == 0
+}
+
+// Next returns the entry following the head with record, without adding it
+func (s *Store) Next(record *circuit.Ciphertext) Entry {
+	head := s.Head()
+	return Entry{Version: head.Version + 1, Record: record, Digest: s.cipher.ChainHistory(head.Digest, record), Issued: head.Issued}
+}
+
+// Contains tells whether the public history of an edit, its version, digest and issued digest
+// as field elements, is a version of the lineage
+func (s *Store) Contains(version *big.Int, digest *big.Int, issued *big.Int) bool {
+	for _, e := range s.entries {
+		if version.Cmp(new(big.Int).SetUint64(e.Version)) == 0 && digest.Cmp(e.Digest) == 0 && issued.Cmp(e.Issued) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Append adds the edit from the head to record, proven from the public history oldVersion,
+// oldDigest to newDigest. It fails if the edit does not start from the head, e.g. a replayed
+// edit or a fork of the lineage, or if record reuses the nonce, and so the keystream, of a
+// version.
+func (s *Store) Append(oldVersion uint64, oldDigest *big.Int, newDigest *big.Int, record *circuit.Ciphertext) (Entry, error) {
+	head := s.Head()
+	if oldVersion != head.Version || oldDigest.Cmp(head.Digest) != 0 {
+		return Entry{}, fmt.Errorf("the edit starts from version %d, the head is version %d", oldVersion, head.Version)
+	}
+	for _, e := range s.entries {
+		if e.Record.Nonce.Cmp(record.Nonce) == 0 {
+			return Entry{}, fmt.Errorf("the record reuses the nonce of version %d", e.Version)
+		}
+	}
+	next := s.Next(record)
+	if newDigest.Cmp(next.Digest) != 0 {
+		return Entry{}, errors.New("the new history digest does not chain the record")
+	}
+	s.entries = append(s.entries, next)
+	return next, nil
+}
+
+// Validate replays the chain of digests and versions, all carrying the digest of the issued
+// record
+func (s *Store) Validate() error {
+	if len(s.entries) == 0 {
+		return errors.New("empty history")
+	}
+	digest := big.NewInt(0)
+	var issued *big.Int
+	for i, e := range s.entries {
+		if e.Record == nil || e.Digest == nil || e.Issued == nil {
+			return fmt.Errorf("version %d is incomplete", i)
+		}
+		if i == 0 {
+			issued = s.cipher.RecordDigest(e.Record.Nonce, e.Record.Blocks)
+		}
+		if e.Issued.Cmp(issued) != 0 {
+			return fmt.Errorf("version %d does not carry the digest of the issued record", i)
+		}
+		if e.Version != uint64(i) {
+			return fmt.Errorf("expected version %d, got %d", i, e.Version)
+		}
+		digest = s.cipher.ChainHistory(digest, e.Record)
+		if digest.Cmp(e.Digest) != 0 {
+			return fmt.Errorf("the digest of version %d does not chain its record", i)
+		}
+	}
+	return nil
+}
+
+type storeJSON struct {
+	Primitive string  `json:"primitive"`
+	Entries   []Entry `json:"entries"`
+}
+
+// Load reads and validates a lineage written by Save, its records encrypted with cipher
+func Load(name string, cipher circuit.Cipher) (*Store, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var f storeJSON
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if f.Primitive != cipher.Primitive.String() {
+		return nil, fmt.Errorf("%s: the lineage is hashed with %s, not %s", name, f.Primitive, cipher.Primitive)
+	}
+	s := &Store{cipher: cipher, entries: f.Entries}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return s, nil
+}
+
+// Save writes the lineage as JSON
+func (s *Store) Save(name string) error {
+	data, err := json.MarshalIndent(storeJSON{Primitive: s.cipher.Primitive.String(), Entries: s.entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0644)
+}
@@ -0,0 +1,80 @@
+package history
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+func TestStore(t *testing.T) {
+	cipher := circuit.Cipher{Curve: ecc.BN254, Primitive: circuit.MimcPrimitive}
+	key := big.NewInt(42)
+	encrypt := func(json string, nonce int64) *circuit.Ciphertext {
+		res, err := cipher.Encrypt([]byte(json), key, big.NewInt(nonce), 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	s := New(cipher, encrypt(`{"Status":"Approved"}`, 1))
+	for i, json := range []string{`{"Status":"Ongoing"}`, `{"Status":"Graduated"}`} {
+		record := encrypt(json, int64(i+2))
+		head, next := s.Head(), s.Next(record)
+		if _, err := s.Append(head.Version, head.Digest, next.Digest, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.Head().Version != 2 {
+		t.Fatalf("expected version 2, got %d", s.Head().Version)
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// replaying the first edit
+	first := s.Entries()[1]
+	if _, err := s.Append(0, s.Entries()[0].Digest, first.Digest, first.Record); err == nil {
+		t.Fatal("expected a replayed edit to be rejected")
+	}
+	reused := encrypt(`{"Status":"Failed"}`, 1)
+	if _, err := s.Append(s.Head().Version, s.Head().Digest, s.Next(reused).Digest, reused); err == nil {
+		t.Fatal("expected a record reusing the nonce of the issued one to be rejected")
+	}
+	record := encrypt(`{"Status":"Failed"}`, 9)
+	if _, err := s.Append(s.Head().Version, s.Head().Digest, first.Digest, record); err == nil {
+		t.Fatal("expected a digest not chaining the record to be rejected")
+	}
+
+	name := filepath.Join(t.TempDir(), "lineage.json")
+	if err := s.Save(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(name, cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Head().Digest.Cmp(s.Head().Digest) != 0 || loaded.Prev().Cmp(s.Entries()[1].Digest) != 0 {
+		t.Fatal("the lineage does not round trip")
+	}
+	if _, err := Load(name, circuit.Cipher{Curve: ecc.BN254, Primitive: circuit.PoseidonPrimitive}); err == nil {
+		t.Fatal("expected a lineage hashed with another primitive to be rejected")
+	}
+
+	head := s.Head()
+	if !s.Contains(big.NewInt(1), first.Digest, first.Issued) || s.Contains(big.NewInt(1), head.Digest, head.Issued) || s.Contains(big.NewInt(2), head.Digest, first.Digest) {
+		t.Fatal("Contains does not match the versions of the lineage")
+	}
+
+	s.entries[2].Issued = first.Digest
+	if err := s.Validate(); err == nil {
+		t.Fatal("expected a version carrying another issued digest to be rejected")
+	}
+	s.entries[2].Issued = head.Issued
+	s.entries[1].Record = record
+	if err := s.Validate(); err == nil {
+		t.Fatal("expected a rewritten version to be rejected")
+	}
+}
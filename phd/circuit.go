@@ -0,0 +1,186 @@
+package phd
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/Nullus-Labs/IDEA-DAC/history"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/signature"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/signature/eddsa"
+)
+
+type PhdEditCircuit struct {
+	OldRecord    circuit.Record  `gnark:",public"`
+	NewRecord    circuit.Record  `gnark:",public"`
+	IssuerKey    eddsa.PublicKey `gnark:",public"`
+	Signature    eddsa.Signature
+	Limit        PhdLimit          `gnark:",public"`
+	CommittedKey frontend.Variable `gnark:",public"`
+	OldHistory   circuit.History   `gnark:",public"`
+	NewHistory   circuit.History   `gnark:",public"`
+	PrevHistory  frontend.Variable
+	ACL          circuit.ACL         `gnark:",public"`
+	Editors      []frontend.Variable `gnark:",public"` // indices in ACL of the editors approving the edit
+	Approvals    []eddsa.Signature
+	OldContent   PhDProfile
+	NewContent   PhDProfile
+	Key          frontend.Variable
+	Blinding     frontend.Variable
+}
+
+func (c *PhdEditCircuit) Define(api frontend.API) error {
+	api = circuit.WithCanonicalJSON(api)
+	circuit.EditCheckPhd(api, c.OldRecord, c.NewRecord, c.OldHistory, c.IssuerKey, c.Signature, c.Limit, c.CommittedKey, c.OldContent, c.NewContent, c.Key, c.Blinding)
+	circuit.CheckHistory(api, c.OldHistory, c.NewHistory, c.PrevHistory, c.OldRecord, c.NewRecord)
+	circuit.CheckEditors(api, circuit.PhdACLPaths, c.ACL, c.Editors, c.Approvals, c.OldRecord, c.NewRecord, c.OldContent, c.NewContent)
+	return nil
+}
+
+// Approval is the signature of an edit by the editor at index Editor in the ACL, see ApproveEdit
+type Approval struct {
+	Editor    int
+	Signature []byte
+}
+
+// GetAssignment returns the witness of the edit from the profile in file oldName to the one in
+// file newName under limit, both encrypted with encryptKey and their own nonce, the key being
+// committed to under blinding. sig is the signature by issuer of the record issued at version 0
+// of the lineage, see SignProfile, checked by every edit.
+// approvals are at most MaxApprovals signatures of the edit by editors of acl, the last one
+// filling the remaining slots. The old profile is the head of lineage, or the issued profile
+// at version 0 if lineage is nil.
+func GetAssignment(oldName string, newName string, limit PhdLimit, encryptKey *fr.Element, blinding *big.Int, oldNonce *big.Int, newNonce *big.Int, issuer signature.PublicKey, sig []byte, acl circuit.ACL, approvals []Approval, lineage *history.Store, MaxPub int) (PhdEditCircuit, error) {
+	res := InitPhdEditCircuit(MaxPub)
+	if oldNonce.Cmp(newNonce) == 0 {
+		return res, errNonceReused
+	}
+	oldEnc, oldProfile, err := ReadJSON(oldName)
+	if err != nil {
+		return res, err
+	}
+	newEnc, newProfile, err := ReadJSON(newName)
+	if err != nil {
+		return res, err
+	}
+	res.OldContent = MakePhdProfile(oldProfile, MaxPub)
+	res.NewContent = MakePhdProfile(newProfile, MaxPub)
+
+	res.Limit = limit
+
+	//Key and committed Key
+	res.Key = encryptKey.BigInt(new(big.Int))
+	res.Blinding = blinding
+	res.CommittedKey = circuit.CommitKey(ecc.BN254, res.Key.(*big.Int), blinding)
+	oldRec, err := Cipher.Encrypt(oldEnc, res.Key.(*big.Int), oldNonce, MaxRecLen)
+	if err != nil {
+		return res, err
+	}
+	newRec, err := Cipher.Encrypt(newEnc, res.Key.(*big.Int), newNonce, MaxRecLen)
+	if err != nil {
+		return res, err
+	}
+	res.OldRecord = oldRec.Record()
+	res.NewRecord = newRec.Record()
+
+	if lineage == nil {
+		lineage = history.New(Cipher, oldRec)
+	}
+	if !lineage.IsHead(oldRec) {
+		return res, errors.New("the old profile is not the head of its lineage")
+	}
+	res.OldHistory = lineage.Head().History()
+	res.NewHistory = lineage.Next(newRec).History()
+	res.PrevHistory = lineage.Prev()
+	if sig == nil {
+		return res, errors.New("missing the signature by the issuer of the issued profile")
+	}
+	res.IssuerKey.Assign(circuit.IssuerCurve(ecc.BN254), issuer.Bytes())
+	res.Signature.Assign(circuit.IssuerCurve(ecc.BN254), sig)
+
+	if len(approvals) == 0 || len(approvals) > MaxApprovals {
+		return res, fmt.Errorf("expected 1 to %d approvals, got %d", MaxApprovals, len(approvals))
+	}
+	res.ACL = acl
+	for i := range res.Editors {
+		a := approvals[len(approvals)-1]
+		if i < len(approvals) {
+			a = approvals[i]
+		}
+		res.Editors[i] = a.Editor
+		res.Approvals[i].Assign(circuit.IssuerCurve(ecc.BN254), a.Signature)
+	}
+	return res, nil
+}
+
+func InitPhdEditCircuit(MaxPub int) PhdEditCircuit {
+	res := PhdEditCircuit{}
+
+	res.OldContent = initPhdProfile(MaxPub)
+	res.NewContent = initPhdProfile(MaxPub)
+
+	//limit
+	res.Limit = initPhdLimit()
+
+	res.Key = 0
+	res.CommittedKey = 0
+	res.Blinding = 0
+
+	res.OldRecord = circuit.EmptyRecord(MaxRecLen)
+	res.NewRecord = circuit.EmptyRecord(MaxRecLen)
+	res.IssuerKey = circuit.EmptyIssuerKey()
+	res.Signature = circuit.EmptySignature()
+
+	res.OldHistory = circuit.EmptyHistory()
+	res.NewHistory = circuit.EmptyHistory()
+	res.PrevHistory = 0
+
+	res.ACL = circuit.EmptyACL(MaxEditors, len(circuit.PhdACLPaths))
+	res.Editors = make([]frontend.Variable, MaxApprovals)
+	res.Approvals = make([]eddsa.Signature, MaxApprovals)
+	for i := range res.Editors {
+		res.Editors[i] = 0
+		res.Approvals[i] = circuit.EmptySignature()
+	}
+	return res
+}
+
+func initPhdProfile(MaxPub int) PhDProfile {
+	res := PhDProfile{}
+	res.Status = EmptyStringNormal()
+	res.ProgramYear = EmptyInteger(1)
+	res.Duration = initTimeRange()
+	res.StudentID = EmptyID()
+	res.Publications = make([]Publication, MaxPub)
+	for i := 0; i < MaxPub; i++ {
+		res.Publications[i] = EmptyPublication()
+	}
+	return res
+}
+
+func initTimeRange() TimeRange {
+	res := TimeRange{}
+	res.Start = EmptyInteger(10)
+	res.End = EmptyInteger(10)
+	return res
+}
+
+func initPhdLimit() PhdLimit {
+	res := PhdLimit{}
+	res.StatusSet = [4]String{EmptyStringNormal(),
+		EmptyStringNormal(),
+		EmptyStringNormal(),
+		EmptyStringNormal()}
+	res.YearRange = [2]frontend.Variable{0, 0}
+
+	res.Format = make([]frontend.Variable, IDLength)
+	for i := 0; i < IDLength; i++ {
+		res.Format[i] = 0
+	}
+	res.TimeMinRange = EmptyInteger(1)
+	return res
+}
@@ -0,0 +1,89 @@
+package phd
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/Nullus-Labs/IDEA-DAC/history"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+)
+
+type phdValidateCircuit struct {
+	Record       circuit.Record    `gnark:",public"`
+	CommittedKey frontend.Variable `gnark:",public"`
+	MinYear      frontend.Variable `gnark:",public"`
+	Content      PhDProfile
+	Key          frontend.Variable
+	Blinding     frontend.Variable
+}
+
+func (c *phdValidateCircuit) Define(api frontend.API) error {
+	circuit.Validate(api, c.Content, c.Record, c.CommittedKey, c.Key, c.Blinding, c.MinYear)
+	return nil
+}
+
+// TestCompileBackends checks that the edit and validate circuits compile for both Groth16 and
+// PLONK
+func TestCompileBackends(t *testing.T) {
+	edit := InitPhdEditCircuit(3)
+	validate := phdValidateCircuit{Record: circuit.EmptyRecord(MaxRecLen), Content: initPhdProfile(3)}
+	for name, builder := range map[string]frontend.NewBuilder{"r1cs": r1cs.NewBuilder, "scs": scs.NewBuilder} {
+		for _, c := range []frontend.Circuit{&edit, &validate} {
+			if _, err := frontend.Compile(ecc.BN254.ScalarField(), builder, c); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+	}
+}
+
+// TestGetAssignmentErrors checks that bad inputs are returned as errors rather than panics
+func TestGetAssignmentErrors(t *testing.T) {
+	const oldName, newName = "../cmd/phd_profile/oldProfile.json", "../cmd/phd_profile/newProfile.json"
+	malformed := filepath.Join(t.TempDir(), "malformed.json")
+	if err := os.WriteFile(malformed, []byte(`{"status": "Ongoing",`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadJSON(malformed); err == nil {
+		t.Fatal("expected a malformed profile to be rejected")
+	}
+	key := new(fr.Element).SetUint64(1234)
+	issuer, err := circuit.NewIssuer(ecc.BN254)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := EncryptProfile(newName, key, big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	approval := Approval{Editor: 0, Signature: sig}
+	for _, tc := range []struct {
+		name      string
+		oldName   string
+		newNonce  int64
+		lineage   *history.Store
+		sig       []byte
+		approvals []Approval
+	}{
+		{"malformed profile", malformed, 2, nil, sig, []Approval{approval}},
+		{"old profile not the head", oldName, 2, history.New(Cipher, other), sig, []Approval{approval}},
+		{"too many approvals", oldName, 2, nil, sig, []Approval{approval, approval, approval}},
+		{"nonce reused", oldName, 1, nil, sig, []Approval{approval}},
+		{"missing signature", oldName, 2, nil, nil, []Approval{approval}},
+	} {
+		acl := circuit.EmptyACL(MaxEditors, len(circuit.PhdACLPaths))
+		if _, err := GetAssignment(tc.oldName, newName, initPhdLimit(), key, big.NewInt(99), big.NewInt(1), big.NewInt(tc.newNonce), issuer.Public(), tc.sig, acl, tc.approvals, tc.lineage, 3); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+	if _, err := ApproveEdit(issuer, oldName, newName, key, big.NewInt(1), big.NewInt(1)); err == nil {
+		t.Error("expected an approval of an edit reusing the nonce to be rejected")
+	}
+}
@@ -0,0 +1,92 @@
+package phd
+
+import (
+	"errors"
+	"fmt"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/Nullus-Labs/IDEA-DAC/policy"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/signature"
+	"github.com/consensys/gnark/frontend"
+)
+
+// LimitFromPolicy returns the PhdLimit of a policy file, which must hold the rules of
+// circuit.PhdLimit.Rules with values fitting the capacities of PhdEditCircuit
+func LimitFromPolicy(f *policy.File) (PhdLimit, error) {
+	byName := map[string]policy.Rule{}
+	for _, r := range f.Rules {
+		if _, ok := byName[r.String()]; ok {
+			return PhdLimit{}, fmt.Errorf("%s appears twice", r)
+		}
+		byName[r.String()] = r
+	}
+	// The shape of the rules is fixed by the circuit, only their values come from the policy
+	shape := policy.PhdRules(nil, [2]int64{}, nil, 0)
+	if len(f.Rules) != len(shape) {
+		return PhdLimit{}, fmt.Errorf("expected the %d rules of a PhD profile, got %d", len(shape), len(f.Rules))
+	}
+	for _, r := range shape {
+		if _, ok := byName[r.String()]; !ok {
+			return PhdLimit{}, fmt.Errorf("missing %s", r)
+		}
+	}
+
+	res := initPhdLimit()
+	// The status set is padded to the capacity of the circuit with empty strings, which match nothing
+	statusSet := byName["oneOfSet(status)"].Set
+	if len(statusSet) > len(res.StatusSet) {
+		return PhdLimit{}, fmt.Errorf("the status set must have at most %d items, got %d", len(res.StatusSet), len(statusSet))
+	}
+	for i, s := range statusSet {
+		if len(circuit.StringToAscii(s)) >= MaxStrLen {
+			return PhdLimit{}, fmt.Errorf("status %q is longer than %d characters", s, MaxStrLen-1)
+		}
+		res.StatusSet[i] = MakeString(s)
+	}
+
+	yearRange := byName["withinRange(programYear)"]
+	if yearRange.Scale != 0 {
+		return PhdLimit{}, fmt.Errorf("the programYear is an integer, got a scale of %d", yearRange.Scale)
+	}
+	res.YearRange = [2]frontend.Variable{yearRange.Range[0], yearRange.Range[1]}
+
+	minDuration := byName["timeInRange(duration)"].MinDuration
+	if minDuration%circuit.OneYearUnix != 0 || minDuration/circuit.OneYearUnix > 9 {
+		return PhdLimit{}, fmt.Errorf("the minimum duration must be a whole number of years below 10, got %d seconds", minDuration)
+	}
+	res.TimeMinRange = MakeInteger(minDuration/circuit.OneYearUnix, 1)
+
+	format := byName["certainFormat(studentID)"].Format
+	if len(format) != IDLength {
+		return PhdLimit{}, fmt.Errorf("the studentID format must have %d positions, got %d", IDLength, len(format))
+	}
+	for i, code := range format {
+		res.Format[i] = code
+	}
+	return res, nil
+}
+
+// ACLFromPolicy returns the ACL of PhdEditCircuit from the roles of a policy file, keys holding
+// the public key of the editor of each role. The editor of the i-th role is at index i.
+func ACLFromPolicy(f *policy.File, keys map[string]signature.PublicKey) (circuit.ACL, error) {
+	if len(f.Roles) == 0 {
+		return circuit.ACL{}, errors.New("the policy has no roles")
+	}
+	editors := make([]signature.PublicKey, len(f.Roles))
+	fields := make([][]string, len(f.Roles))
+	for i, r := range f.Roles {
+		key, ok := keys[r.Name]
+		if !ok {
+			return circuit.ACL{}, fmt.Errorf("missing the key of role %s", r.Name)
+		}
+		editors[i] = key
+		fields[i] = r.Fields
+	}
+	for name := range keys {
+		if _, ok := f.Role(name); !ok {
+			return circuit.ACL{}, fmt.Errorf("unknown role %s", name)
+		}
+	}
+	return circuit.NewACL(ecc.BN254, circuit.PhdACLPaths, editors, fields, MaxEditors)
+}
@@ -0,0 +1,172 @@
+package phd
+
+import (
+	"math/big"
+	"testing"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/Nullus-Labs/IDEA-DAC/history"
+	"github.com/Nullus-Labs/IDEA-DAC/policy"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/signature"
+	"github.com/consensys/gnark/test"
+)
+
+func TestLimitFromPolicy(t *testing.T) {
+	f, err := policy.Load("../cmd/phd_profile/phdPolicy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit, err := LimitFromPolicy(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	circ := InitPhdEditCircuit(3)
+	key := new(fr.Element).SetUint64(1234)
+	issuer, err := circuit.NewIssuer(ecc.BN254)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := SignProfile(issuer, "../cmd/phd_profile/oldProfile.json", key, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	registrar, err := circuit.NewIssuer(ecc.BN254)
+	if err != nil {
+		t.Fatal(err)
+	}
+	student, err := circuit.NewIssuer(ecc.BN254)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl, err := ACLFromPolicy(f, map[string]signature.PublicKey{"registrar": registrar.Public(), "student": student.Public()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var approvals []Approval
+	for _, editor := range []signature.Signer{registrar, student} {
+		approval, err := ApproveEdit(editor, "../cmd/phd_profile/oldProfile.json", "../cmd/phd_profile/newProfile.json", key, big.NewInt(1), big.NewInt(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		approvals = append(approvals, Approval{Editor: len(approvals), Signature: approval})
+	}
+	assignment, err := GetAssignment("../cmd/phd_profile/oldProfile.json", "../cmd/phd_profile/newProfile.json", limit, key, big.NewInt(99), big.NewInt(1), big.NewInt(2), issuer.Public(), sig, acl, approvals, nil, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := test.IsSolved(&circ, &assignment, ecc.BN254.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetAssignment("../cmd/phd_profile/oldProfile.json", "../cmd/phd_profile/newProfile.json", limit, key, big.NewInt(99), big.NewInt(1), big.NewInt(2), issuer.Public(), nil, acl, approvals, nil, 3); err == nil {
+		t.Fatal("expected an edit without the signature of the issuer to be rejected")
+	}
+	unsigned := assignment
+	unsigned.Signature = circuit.EmptySignature()
+	if err := test.IsSolved(&circ, &unsigned, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected an issued profile without signature to be rejected")
+	}
+	// a version 1 forged from the old profile, opened with any previous digest, still lacks the
+	// signature of an issued record
+	oldRec, err := EncryptProfile("../cmd/phd_profile/oldProfile.json", key, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newRec, err := EncryptProfile("../cmd/phd_profile/newProfile.json", key, big.NewInt(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := unsigned
+	forged.PrevHistory = big.NewInt(12345)
+	forged.OldHistory = circuit.History{Version: 1, Digest: Cipher.ChainHistory(big.NewInt(12345), oldRec), Issued: Cipher.RecordDigest(oldRec.Nonce, oldRec.Blocks)}
+	forged.NewHistory = circuit.History{Version: 2, Digest: Cipher.ChainHistory(forged.OldHistory.Digest.(*big.Int), newRec), Issued: forged.OldHistory.Issued}
+	if err := test.IsSolved(&circ, &forged, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected a forged version 1 without signature to be rejected")
+	}
+
+	// past version 0 the signature is the one of the issued profile
+	issued, err := EncryptProfile("../cmd/phd_profile/newProfile.json", key, big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuedSig, err := Cipher.Sign(issuer, issued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lineage := history.New(Cipher, issued)
+	if _, err := lineage.Append(0, lineage.Head().Digest, lineage.Next(oldRec).Digest, oldRec); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		sig   []byte
+		valid bool
+	}{
+		{issuedSig, true},
+		{sig, false},
+	} {
+		assignment, err = GetAssignment("../cmd/phd_profile/oldProfile.json", "../cmd/phd_profile/newProfile.json", limit, key, big.NewInt(99), big.NewInt(1), big.NewInt(2), issuer.Public(), tc.sig, acl, approvals, lineage, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := test.IsSolved(&circ, &assignment, ecc.BN254.ScalarField()); (err == nil) != tc.valid {
+			t.Fatalf("expected valid %v, got %v", tc.valid, err)
+		}
+	}
+	// the student may not change the status
+	assignment, err = GetAssignment("../cmd/phd_profile/oldProfile.json", "../cmd/phd_profile/newProfile.json", limit, key, big.NewInt(99), big.NewInt(1), big.NewInt(2), issuer.Public(), sig, acl, approvals[1:], nil, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := test.IsSolved(&circ, &assignment, ecc.BN254.ScalarField()); err == nil {
+		t.Fatal("expected an edit of the status without the registrar to be rejected")
+	}
+	if _, err := ACLFromPolicy(f, map[string]signature.PublicKey{"registrar": registrar.Public()}); err == nil {
+		t.Fatal("expected a role without key to be rejected")
+	}
+
+	// a shorter status set is padded with items matching nothing
+	hash := f.Hash()
+	for _, tc := range []struct {
+		set   []string
+		valid bool
+	}{
+		{[]string{"Approved", "Ongoing"}, true},
+		{[]string{"Approved", "Graduated"}, false},
+	} {
+		f.Rules[1].Set = tc.set
+		limit, err := LimitFromPolicy(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assignment, err = GetAssignment("../cmd/phd_profile/oldProfile.json", "../cmd/phd_profile/newProfile.json", limit, key, big.NewInt(99), big.NewInt(1), big.NewInt(2), issuer.Public(), sig, acl, approvals, nil, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := test.IsSolved(&circ, &assignment, ecc.BN254.ScalarField()); (err == nil) != tc.valid {
+			t.Fatalf("status set %q: expected valid %v, got %v", tc.set, tc.valid, err)
+		}
+	}
+	if f.Hash() == hash {
+		t.Fatal("the policy hash does not depend on the rules")
+	}
+	f.Rules[1].Set = []string{"Approved", "Ongoing", "Graduated", "Failed", "Suspended"}
+	if _, err := LimitFromPolicy(f); err == nil {
+		t.Fatal("expected a status set of 5 items to be rejected")
+	}
+	f.Rules[1].Set = []string{"Approved", ""}
+	if err := f.Validate(); err == nil {
+		t.Fatal("expected an empty status to be rejected")
+	}
+	f.Rules[1].Set = []string{"Approved", "Ongoing"}
+	f.Rules[2].Scale = 1
+	if _, err := LimitFromPolicy(f); err == nil {
+		t.Fatal("expected a scaled programYear range to be rejected")
+	}
+	f.Rules[2].Scale = 0
+	f.Rules[1].Set = []string{"Approved", "Ongoing", "Graduated", "Approved"}
+	if err := f.Validate(); err == nil {
+		t.Fatal("expected a duplicated status to be rejected")
+	}
+}
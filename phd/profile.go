@@ -0,0 +1,186 @@
+// Package phd holds the PhD profile credential shared by the example commands: its edit
+// circuit, the helpers building its witness from the profile JSON files and record encryption.
+package phd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/consensys/gnark/frontend"
+)
+
+// const MaxPub = 3
+const BlkLen = 31
+const MaxRecLen = 100
+const MaxStrLen = 20
+const MaxTitleLen = 100
+const MaxDepth = 3
+const IDLength = 5
+
+// MaxEditors is the number of editors of the ACL of PhdEditCircuit, MaxApprovals the number of
+// editors approving an edit
+const MaxEditors = 4
+const MaxApprovals = 2
+
+type String = circuit.String
+type Integer = circuit.Integer
+type Publication = circuit.Publication
+type PhDProfile = circuit.PhDProfile
+type PhdLimit = circuit.PhdLimit
+type TimeRange = circuit.TimeRange
+
+type PhDProfileJSON struct {
+	Status       string            `json:"status"`
+	ProgramYear  int64             `json:"programYear"`
+	StudentID    string            `json:"studentID"`
+	Publications []PublicationJSON `json:"publications"`
+	Duration     TimeRangeJSON     `json:"duration"`
+}
+
+type PublicationJSON struct {
+	Title string `json:"title"`
+	Year  int64  `json:"year"`
+}
+
+type TimeRangeJSON struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+func MakeString(input string) String {
+	ascii := circuit.StringToAscii(input)
+	x := make(String, MaxStrLen)
+	x[0] = len(ascii)
+	for i := 1; i < len(ascii)+1; i++ {
+		x[i] = ascii[i-1]
+	}
+	for i := len(ascii) + 1; i < MaxStrLen; i++ {
+		x[i] = circuit.DUMMY
+	}
+	return x
+}
+
+func MakeTitle(input string) String {
+	ascii := circuit.StringToAscii(input)
+	x := make(String, MaxTitleLen)
+	x[0] = len(ascii)
+	for i := 1; i < len(ascii)+1; i++ {
+		x[i] = ascii[i-1]
+	}
+	for i := len(ascii) + 1; i < MaxTitleLen; i++ {
+		x[i] = circuit.DUMMY
+	}
+	return x
+}
+
+func MakeID(input string) String {
+	ascii := circuit.StringToAscii(input)
+	x := make(String, IDLength+1)
+	x[0] = len(ascii)
+	for i := 0; i < len(ascii); i++ {
+		x[i+1] = ascii[i]
+	}
+	return x
+}
+
+func EmptyStringNormal() String {
+	ret := make(String, MaxStrLen)
+	ret[0] = 0
+	for i := 1; i < MaxStrLen; i++ {
+		ret[i] = circuit.DUMMY
+	}
+	return ret
+}
+
+func EmptyID() String {
+	ret := make(String, IDLength+1)
+	ret[0] = IDLength
+	for i := 1; i < IDLength+1; i++ {
+		ret[i] = circuit.DUMMY
+	}
+	return ret
+}
+
+func EmptyStringTitle() String {
+	ret := make(String, MaxTitleLen)
+	ret[0] = 0
+	for i := 1; i < MaxTitleLen; i++ {
+		ret[i] = circuit.DUMMY
+	}
+	return ret
+}
+
+func EmptyInteger(maxDigit ...int) Integer {
+	if len(maxDigit) > 0 {
+		return Integer{
+			X:        0,
+			MaxDigit: maxDigit[0]}
+	} else {
+		return Integer{
+			X:        0,
+			MaxDigit: 0}
+	}
+}
+
+func EmptyPublication() Publication {
+	return Publication{
+		Title: EmptyStringTitle(),
+		Year:  EmptyInteger(4)}
+}
+
+func MakeInteger(x int64, maxDigit int) Integer {
+	return Integer{
+		X:        frontend.Variable(x),
+		MaxDigit: maxDigit}
+}
+
+func MakeTimeRange(start int64, end int64) TimeRange {
+	return TimeRange{
+		Start: MakeInteger(start, 10),
+		End:   MakeInteger(end, 10)}
+}
+
+func MakePublication(title string, year int64) Publication {
+	return Publication{
+		Title: MakeTitle(title),
+		Year:  MakeInteger(year, 4)}
+}
+
+func MakePhdProfile(profile PhDProfileJSON, MaxPub int) PhDProfile {
+	res := initPhdProfile(MaxPub)
+	res.Status = MakeString(profile.Status)
+	res.ProgramYear = MakeInteger(profile.ProgramYear, 1)
+	res.Duration = MakeTimeRange(profile.Duration.Start, profile.Duration.End)
+	res.StudentID = MakeID(profile.StudentID)
+	res.Publications = make([]Publication, MaxPub)
+	for i := 0; i < MaxPub; i++ {
+		res.Publications[i] = EmptyPublication()
+	}
+	for i := 0; i < len(profile.Publications); i++ {
+		res.Publications[i] = MakePublication(profile.Publications[i].Title, profile.Publications[i].Year)
+	}
+
+	return res
+}
+
+// ReadJSON returns the profile in file name in canonical JSON, as PhdEditCircuit encodes it, and
+// decoded
+func ReadJSON(name string) ([]byte, PhDProfileJSON, error) {
+	var profile PhDProfileJSON
+	// Read the JSON file
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, profile, err
+	}
+	canonical, err := circuit.CanonicalJSON(data)
+	if err != nil {
+		return nil, profile, fmt.Errorf("%s: %w", name, err)
+	}
+	// Unmarshal the JSON data into the struct
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, profile, fmt.Errorf("%s: %w", name, err)
+	}
+	return canonical, profile, nil
+}
@@ -0,0 +1,73 @@
+package phd
+
+import (
+	"errors"
+	"math/big"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/signature"
+)
+
+// EncryptRec encrypts an encoded profile under key and the random nonce of the record
+func EncryptRec(input []byte, key *fr.Element, nonce *big.Int) []fr.Element {
+	blocks := circuit.EncryptRecord(ecc.BN254, input, key.BigInt(new(big.Int)), nonce)
+	res := make([]fr.Element, len(blocks))
+	for i := range blocks {
+		res[i].SetBigInt(blocks[i])
+	}
+	return res
+}
+
+// Cipher is the encryption of the records of PhdEditCircuit
+var Cipher = circuit.Cipher{Curve: ecc.BN254, Primitive: circuit.MimcPrimitive}
+
+// EncryptProfile encrypts the profile in file name under key and nonce as GetAssignment does
+func EncryptProfile(name string, key *fr.Element, nonce *big.Int) (*circuit.Ciphertext, error) {
+	enc, _, err := ReadJSON(name)
+	if err != nil {
+		return nil, err
+	}
+	return Cipher.Encrypt(enc, key.BigInt(new(big.Int)), nonce, MaxRecLen)
+}
+
+// SignProfile is the signature by issuer of the profile in file name, encrypted under key and
+// nonce as GetAssignment does
+func SignProfile(issuer signature.Signer, name string, key *fr.Element, nonce *big.Int) ([]byte, error) {
+	rec, err := EncryptProfile(name, key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return Cipher.Sign(issuer, rec)
+}
+
+// errNonceReused is returned for an edit encrypting both profiles under the same nonce, whose
+// keystreams would cancel out in the difference of the records
+var errNonceReused = errors.New("the old and new profiles must be encrypted under different nonces")
+
+// ApproveEdit is the approval by editor of the edit from the profile in file oldName to the one
+// in file newName, encrypted under key and their nonces as GetAssignment does
+func ApproveEdit(editor signature.Signer, oldName string, newName string, key *fr.Element, oldNonce *big.Int, newNonce *big.Int) ([]byte, error) {
+	if oldNonce.Cmp(newNonce) == 0 {
+		return nil, errNonceReused
+	}
+	oldRec, err := EncryptProfile(oldName, key, oldNonce)
+	if err != nil {
+		return nil, err
+	}
+	newRec, err := EncryptProfile(newName, key, newNonce)
+	if err != nil {
+		return nil, err
+	}
+	return Cipher.SignEdit(editor, oldRec, newRec)
+}
+
+// DecryptRec returns the encoded profile of the blocks produced by EncryptRec
+func DecryptRec(key *fr.Element, nonce *big.Int, blocks []fr.Element) ([]byte, error) {
+	res := make([]*big.Int, len(blocks))
+	for i := range blocks {
+		res[i] = blocks[i].BigInt(new(big.Int))
+	}
+	return circuit.DecryptRecord(ecc.BN254, res, key.BigInt(new(big.Int)), nonce)
+}
@@ -0,0 +1,204 @@
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/Nullus-Labs/IDEA-DAC/dfa"
+)
+
+// File is a versioned set of rules, e.g. the edit policy of a credential type maintained by its
+// issuer:
+//
+//	{
+//	  "name": "phd-profile",
+//	  "version": 2,
+//	  "rules": [
+//	    {"path": "status", "kind": "oneOfSet", "set": ["Approved", "Ongoing", "Graduated", "Failed"]},
+//	    {"path": "programYear", "kind": "withinRange", "range": [0, 10]}
+//	  ],
+//	  "roles": [
+//	    {"name": "registrar", "fields": ["status", "programYear"]}
+//	  ]
+//	}
+//
+// Roles are optional, a policy without them leaves the editors of a record unrestricted.
+type File struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	Rules   []Rule `json:"rules"`
+	Roles   []Role `json:"roles,omitempty"`
+}
+
+// Role names the editors allowed to change some fields of a record, e.g. the registrar may
+// change the status of a student. The editor keys holding each role are bound to the circuit
+// outside the policy, see phd.ACLFromPolicy.
+type Role struct {
+	Name   string   `json:"name"`
+	Fields []string `json:"fields"` // paths, as in Rule
+}
+
+// Load reads and validates a policy file
+func Load(name string) (*File, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &f, nil
+}
+
+// Validate checks that every rule is well formed, whatever the record it applies to
+func (f *File) Validate() error {
+	if f.Name == "" {
+		return errors.New("missing name")
+	}
+	if f.Version < 1 {
+		return errors.New("version must be positive")
+	}
+	if len(f.Rules) == 0 {
+		return errors.New("no rules")
+	}
+	for _, r := range f.Rules {
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("%s: %w", r, err)
+		}
+	}
+	names := map[string]bool{}
+	for _, r := range f.Roles {
+		if r.Name == "" {
+			return errors.New("missing role name")
+		}
+		if names[r.Name] {
+			return fmt.Errorf("role %s appears twice", r.Name)
+		}
+		names[r.Name] = true
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("role %s: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// Role returns the index of the role called name, which is the index of its editor in the ACL
+// of the circuit
+func (f *File) Role(name string) (int, bool) {
+	for i, r := range f.Roles {
+		if r.Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Hash identifies the policy, it changes whenever the name, version or any rule does
+func (f *File) Hash() string {
+	data, err := json.Marshal(f)
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Uncovered returns the fields at paths that differ between oldRecord and newRecord and that
+// none of the approving roles may change, as circuit.CheckEditors would reject them
+func (f *File) Uncovered(paths []string, approving []string, oldRecord interface{}, newRecord interface{}) ([]string, error) {
+	allowed := map[string]bool{}
+	for _, name := range approving {
+		i, ok := f.Role(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown role %s", name)
+		}
+		for _, field := range f.Roles[i].Fields {
+			allowed[field] = true
+		}
+	}
+	var res []string
+	for _, path := range paths {
+		oldValue, oldErr := valueByPath(oldRecord, path)
+		newValue, newErr := valueByPath(newRecord, path)
+		changed := (oldErr == nil) != (newErr == nil) || !reflect.DeepEqual(oldValue, newValue)
+		if changed && !allowed[path] {
+			res = append(res, path)
+		}
+	}
+	return res, nil
+}
+
+func (r Role) validate() error {
+	if len(r.Fields) == 0 {
+		return errors.New("no fields")
+	}
+	seen := map[string]bool{}
+	for _, field := range r.Fields {
+		if field == "" {
+			return errors.New("empty field")
+		}
+		if seen[field] {
+			return fmt.Errorf("%s appears twice", field)
+		}
+		seen[field] = true
+	}
+	return nil
+}
+
+func (r Rule) validate() error {
+	if r.Path == "" {
+		return errors.New("missing path")
+	}
+	switch r.Kind {
+	case Immutable, AppendOnly:
+	case OneOfSet, MerkleSet:
+		if len(r.Set) == 0 {
+			return errors.New("empty set")
+		}
+		seen := map[string]bool{}
+		for _, s := range r.Set {
+			// empty items pad the sets of oneOfSet and match nothing, and circuit.NewMerkleSet
+			// rejects duplicated leaves
+			if s == "" && r.Kind == OneOfSet {
+				return errors.New("empty item in the set")
+			}
+			if seen[s] {
+				return fmt.Errorf("%q appears twice in the set", s)
+			}
+			seen[s] = true
+		}
+	case WithinRange:
+		if r.Range[0] > r.Range[1] || r.Scale < 0 {
+			return fmt.Errorf("invalid range %v with scale %d", r.Range, r.Scale)
+		}
+	case TimeInRange:
+		if r.MinDuration < 0 {
+			return errors.New("negative minDuration")
+		}
+	case CertainFormat:
+		if len(r.Format) == 0 {
+			return errors.New("empty format")
+		}
+		for _, code := range r.Format {
+			if code < 1 || code > 4 {
+				return fmt.Errorf("invalid format code %d", code)
+			}
+		}
+	case RegexFormat:
+		if _, err := dfa.Compile(r.Pattern); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown rule kind %q", r.Kind)
+	}
+	return nil
+}
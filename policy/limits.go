@@ -0,0 +1,27 @@
+package policy
+
+import circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+
+// PhdRules mirrors circuit.PhdLimit.Rules, minYears being the TimeMinRange of the limit
+func PhdRules(statusSet []string, yearRange [2]int64, format []int, minYears int64) []Rule {
+	return []Rule{
+		{Path: "publications", Kind: AppendOnly},
+		{Path: "status", Kind: OneOfSet, Set: statusSet},
+		{Path: "programYear", Kind: WithinRange, Range: yearRange},
+		{Path: "duration", Kind: TimeInRange, MinDuration: minYears * circuit.OneYearUnix},
+		{Path: "studentID", Kind: CertainFormat, Format: format},
+		{Path: "studentID", Kind: Immutable},
+	}
+}
+
+// CovidRules mirrors circuit.CovidLimit.Rules
+func CovidRules(vaccineTypeSet []string, dosageMax int64, medicalInsuranceStatusSet []string, coverageMaxEndDate int64, format []int) []Rule {
+	return []Rule{
+		{Path: "LatestVaccine.VaccineType", Kind: OneOfSet, Set: vaccineTypeSet},
+		{Path: "LatestVaccine.Dosage", Kind: WithinRange, Range: [2]int64{0, dosageMax}},
+		{Path: "CovidTest", Kind: AppendOnly},
+		{Path: "CovidTestNumber", Kind: CertainFormat, Format: format},
+		{Path: "MedicalInsuranceStatus", Kind: OneOfSet, Set: medicalInsuranceStatusSet},
+		{Path: "CoverageEndDate", Kind: WithinRange, Range: [2]int64{0, coverageMaxEndDate}},
+	}
+}
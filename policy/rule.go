@@ -0,0 +1,325 @@
+// Package policy evaluates the edit bounds of the circuit package natively, so that an edit
+// can be checked before any proof is computed.
+//
+// Records are plain JSON values as decoded by encoding/json with UseNumber. Every rule gives
+// the same verdict as its circuit counterpart, including the corner cases documented on Check.
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/Nullus-Labs/IDEA-DAC/dfa"
+)
+
+// Kind is the edit bound enforced on a field, named as in the x-edit keyword of zkgen
+type Kind string
+
+const (
+	Immutable     Kind = "immutable"     // circuit.RuleImmutable
+	AppendOnly    Kind = "appendOnly"    // circuit.RuleAppendOnly
+	OneOfSet      Kind = "oneOfSet"      // circuit.RuleOneOfSet
+	WithinRange   Kind = "withinRange"   // circuit.RuleWithinRange
+	TimeInRange   Kind = "timeInRange"   // circuit.RuleTimeInRange
+	CertainFormat Kind = "certainFormat" // circuit.RuleCertainFormat
+	MerkleSet     Kind = "merkleSet"     // circuit.RuleMerkleSet, Set holds the leaves of the tree
+	RegexFormat   Kind = "regexFormat"   // circuit.RuleRegexFormat
+)
+
+// Rule is the native counterpart of circuit.Rule
+type Rule struct {
+	Path        string   `json:"path"` // dot separated keys, e.g. "LatestVaccine.Dosage"
+	Kind        Kind     `json:"kind"`
+	Set         []string `json:"set,omitempty"`
+	Range       [2]int64 `json:"range,omitempty"`       // withinRange, in units of 10^-Scale
+	Scale       int      `json:"scale,omitempty"`       // withinRange, number of decimals of the value, see circuit.Integer
+	MinDuration int64    `json:"minDuration,omitempty"` // in seconds
+	Format      []int    `json:"format,omitempty"`      // 1: capital letter, 2: small letter, 3: number, 4: special character
+	Pattern     string   `json:"pattern,omitempty"`     // regexFormat, matched against the whole string, see dfa.Compile
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%s(%s)", r.Kind, r.Path)
+}
+
+// Violation tells which rule an edit breaks and why
+type Violation struct {
+	Rule   Rule
+	Reason string
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Reason)
+}
+
+// Check evaluates rules on the edit from oldRecord to newRecord and returns the broken ones.
+//
+// As in the circuit:
+//   - appendOnly lets the first element that differs change freely as long as no element
+//     follows it in either array
+//   - certainFormat accepts B-Z as capital letters, b-z as small letters, 1-9 as numbers and
+//     '"' to '/' as special characters, checks the first len(Format) characters and ignores
+//     the remaining ones
+func Check(rules []Rule, oldRecord interface{}, newRecord interface{}) []Violation {
+	var res []Violation
+	for _, rule := range rules {
+		if reason := rule.check(oldRecord, newRecord); reason != "" {
+			res = append(res, Violation{Rule: rule, Reason: reason})
+		}
+	}
+	return res
+}
+
+// CheckJSON decodes both records and checks them
+func CheckJSON(rules []Rule, oldJSON []byte, newJSON []byte) ([]Violation, error) {
+	oldRecord, err := Decode(oldJSON)
+	if err != nil {
+		return nil, err
+	}
+	newRecord, err := Decode(newJSON)
+	if err != nil {
+		return nil, err
+	}
+	return Check(rules, oldRecord, newRecord), nil
+}
+
+// Decode unmarshals a record keeping numbers as json.Number
+func Decode(data []byte) (interface{}, error) {
+	var res interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r Rule) check(oldRecord interface{}, newRecord interface{}) string {
+	oldValue, oldErr := valueByPath(oldRecord, r.Path)
+	newValue, newErr := valueByPath(newRecord, r.Path)
+	if newErr != nil {
+		return newErr.Error()
+	}
+	if oldErr != nil && (r.Kind == Immutable || r.Kind == AppendOnly) {
+		return oldErr.Error()
+	}
+	switch r.Kind {
+	case Immutable:
+		if !reflect.DeepEqual(oldValue, newValue) {
+			return fmt.Sprintf("changed from %v to %v", oldValue, newValue)
+		}
+	case AppendOnly:
+		return checkAppendOnly(oldValue, newValue)
+	case OneOfSet:
+		return checkOneOfSet(r.Set, newValue)
+	case WithinRange:
+		x, err := number(newValue, r.Scale)
+		if err != nil {
+			return err.Error()
+		}
+		if x.Cmp(big.NewInt(r.Range[0])) < 0 || x.Cmp(big.NewInt(r.Range[1])) > 0 {
+			return fmt.Sprintf("%v is not within [%d, %d]", x, r.Range[0], r.Range[1])
+		}
+	case TimeInRange:
+		return checkTimeInRange(r.MinDuration, newValue)
+	case CertainFormat:
+		return checkFormat(r.Format, newValue)
+	case MerkleSet:
+		return checkMerkleSet(r.Set, newValue)
+	case RegexFormat:
+		return checkRegex(r.Pattern, newValue)
+	default:
+		return fmt.Sprintf("unknown rule kind %q", r.Kind)
+	}
+	return ""
+}
+
+func checkAppendOnly(oldValue interface{}, newValue interface{}) string {
+	oldArr, ok1 := oldValue.([]interface{})
+	newArr, ok2 := newValue.([]interface{})
+	if !ok1 || !ok2 {
+		return "not an array"
+	}
+	n := len(oldArr)
+	if len(newArr) > n {
+		n = len(newArr)
+	}
+	// missing elements are the empty items padding the circuit arrays
+	at := func(arr []interface{}, i int) interface{} {
+		if i < len(arr) {
+			return arr[i]
+		}
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		if !reflect.DeepEqual(at(oldArr, i), at(newArr, i)) {
+			if i+1 < n {
+				return fmt.Sprintf("element %d is modified or removed", i)
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// checkOneOfSet is a membership check over the items of set, an empty item being the padding of
+// the circuit set and matching nothing
+func checkOneOfSet(set []string, value interface{}) string {
+	s, ok := value.(string)
+	if !ok {
+		return "not a string"
+	}
+	for _, item := range set {
+		if item != "" && item == s {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%q is not one of %q", s, set)
+}
+
+// checkMerkleSet is a membership check too, NewMerkleSet already rejects duplicated leaves
+func checkMerkleSet(set []string, value interface{}) string {
+	s, ok := value.(string)
+	if !ok {
+		return "not a string"
+	}
+	for _, item := range set {
+		if item == s {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%q is not in the Merkle set", s)
+}
+
+func checkRegex(pattern string, value interface{}) string {
+	s, ok := value.(string)
+	if !ok {
+		return "not a string"
+	}
+	d, err := dfa.Compile(pattern)
+	if err != nil {
+		return err.Error()
+	}
+	if !d.Match(s) {
+		return fmt.Sprintf("%q does not match %s", s, pattern)
+	}
+	return ""
+}
+
+func checkTimeInRange(minDuration int64, value interface{}) string {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return "not an object with Start and End"
+	}
+	start, err := integer(member(obj, "Start"))
+	if err != nil {
+		return "Start: " + err.Error()
+	}
+	end, err := integer(member(obj, "End"))
+	if err != nil {
+		return "End: " + err.Error()
+	}
+	if new(big.Int).Add(start, big.NewInt(minDuration)).Cmp(end) >= 0 {
+		return fmt.Sprintf("End %v is not more than %d seconds after Start %v", end, minDuration, start)
+	}
+	return ""
+}
+
+// member is the value of obj at key, matched case-insensitively as encoding/json matches struct
+// fields, e.g. "start" for Start
+func member(obj map[string]interface{}, key string) interface{} {
+	if v, ok := obj[key]; ok {
+		return v
+	}
+	for k, v := range obj {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return nil
+}
+
+func checkFormat(format []int, value interface{}) string {
+	s, ok := value.(string)
+	if !ok {
+		return "not a string"
+	}
+	chars := []byte(s) // the circuit checks the bytes of the UTF-8 encoding
+	for i, code := range format {
+		if i >= len(chars) {
+			return fmt.Sprintf("%q is shorter than the format", s)
+		}
+		c := chars[i]
+		var valid bool
+		switch code {
+		case 1:
+			valid = 'A' < c && c <= 'Z'
+		case 2:
+			valid = 'a' < c && c <= 'z'
+		case 3:
+			valid = '0' < c && c <= '9'
+		case 4:
+			valid = '!' < c && c <= '/'
+		}
+		if !valid {
+			return fmt.Sprintf("character %d %q of %q does not meet format %d", i, c, s, code)
+		}
+	}
+	return ""
+}
+
+// integer returns the value of a non-negative JSON integer, e.g. a Unix time
+func integer(value interface{}) (*big.Int, error) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a number", value)
+	}
+	x, ok := new(big.Int).SetString(n.String(), 10)
+	if !ok || x.Sign() < 0 {
+		return nil, fmt.Errorf("%v is not a non-negative integer", n)
+	}
+	return x, nil
+}
+
+// number returns a signed JSON number with at most scale decimals times 10^scale, written as
+// encodeNumber writes it or as circuit.CanonicalJSON does without the trailing zeros, e.g.
+// -1250 for -12.50 or -12.5 with scale 2
+func number(value interface{}, scale int) (*big.Int, error) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a number", value)
+	}
+	text := n.String()
+	digits := strings.TrimPrefix(text, "-")
+	integral, fraction := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		integral, fraction = digits[:i], digits[i+1:]
+	}
+	x, ok := new(big.Int).SetString(integral+fraction, 10)
+	if !ok || len(fraction) > scale || strings.ContainsAny(integral+fraction, "+-") || integral == "" || (integral != "0" && integral[0] == '0') || (x.Sign() == 0 && digits != text) {
+		return nil, fmt.Errorf("%v is not a number with %d decimals", n, scale)
+	}
+	x.Mul(x, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale-len(fraction))), nil))
+	if digits != text {
+		x.Neg(x)
+	}
+	return x, nil
+}
+
+func valueByPath(record interface{}, path string) (interface{}, error) {
+	v := record
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid path %s", path)
+		}
+		if v, ok = obj[key]; !ok {
+			return nil, fmt.Errorf("missing %s", path)
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,169 @@
+package policy
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	circuit "github.com/Nullus-Labs/IDEA-DAC/circuit"
+)
+
+var testRules = PhdRules([]string{"Approved", "Ongoing", "Graduated", "Failed"}, [2]int64{0, 10}, []int{1, 1, 1, 3, 3}, 3)
+
+func readProfiles(t *testing.T) (string, string) {
+	oldJSON, err := os.ReadFile("../cmd/phd_profile/oldProfile.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newJSON, err := os.ReadFile("../cmd/phd_profile/newProfile.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(oldJSON), string(newJSON)
+}
+
+func TestCheckPhdProfile(t *testing.T) {
+	oldJSON, newJSON := readProfiles(t)
+	violations, err := CheckJSON(testRules, []byte(oldJSON), []byte(newJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 0 {
+		t.Fatal(violations)
+	}
+
+	cases := []struct {
+		name    string
+		old     string
+		new     string
+		failing string
+	}{
+		{"status", oldJSON, strings.Replace(newJSON, `"Ongoing"`, `"Paused"`, 1), "oneOfSet(status)"},
+		{"year", oldJSON, strings.Replace(newJSON, `"programYear": 5`, `"programYear": 11`, 1), "withinRange(programYear)"},
+		{"duration", oldJSON, strings.Replace(newJSON, `1781941354`, `1561016555`, 1), "timeInRange(duration)"},
+		// 'A' is not accepted as a capital letter by checkFormat
+		{"format", strings.Replace(oldJSON, "UNI42", "ANI42", 1), strings.Replace(newJSON, "UNI42", "ANI42", 1), "certainFormat(studentID)"},
+		{"id", oldJSON, strings.Replace(newJSON, "UNI42", "UNI43", 1), "immutable(studentID)"},
+		{"modified publication", oldJSON, strings.Replace(newJSON, "ZK-Profile", "ZK-Proof", 1), "appendOnly(publications)"},
+	}
+	for _, c := range cases {
+		violations, err := CheckJSON(testRules, []byte(c.old), []byte(c.new))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(violations) != 1 || violations[0].Rule.String() != c.failing {
+			t.Errorf("%s: expected %s to fail, got %v", c.name, c.failing, violations)
+		}
+	}
+}
+
+func TestCheckWithinRangeDecimal(t *testing.T) {
+	rules := []Rule{{Path: "Balance", Kind: WithinRange, Range: [2]int64{-10000, 500}, Scale: 2}}
+	for _, c := range []struct {
+		balance string
+		valid   bool
+	}{
+		{"-12.50", true},
+		{"5.00", true},
+		{"0.00", true},
+		{"5.01", false},
+		{"-100.01", false},
+		// in canonical JSON
+		{"-12.5", true},
+		{"5", true},
+		{"0", true},
+		{"-100.1", false},
+		// not written as the circuit encodes it
+		{"5.001", false},
+		{"-0.00", false},
+		{"-0", false},
+		{"1.25e1", false},
+	} {
+		record := []byte(`{"Balance":` + c.balance + `}`)
+		violations, err := CheckJSON(rules, record, record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (len(violations) == 0) != c.valid {
+			t.Errorf("%s: got %v", c.balance, violations)
+		}
+	}
+}
+
+func TestCheckCanonicalDecimal(t *testing.T) {
+	rules := []Rule{{Path: "Balance", Kind: WithinRange, Range: [2]int64{-10000, 500}, Scale: 2}}
+	for _, c := range []struct {
+		balance string
+		valid   bool
+	}{
+		{"-12.50", true},
+		{"5.00", true},
+		{"-100.00", true},
+		{"-100.01", false},
+	} {
+		record, err := circuit.CanonicalJSON([]byte(`{"Balance": ` + c.balance + `}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		violations, err := CheckJSON(rules, record, record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (len(violations) == 0) != c.valid {
+			t.Errorf("%s: got %v", record, violations)
+		}
+	}
+}
+
+func TestCheckRegexFormat(t *testing.T) {
+	rules := []Rule{{Path: "ORCID", Kind: RegexFormat, Pattern: `\d{4}-\d{4}-\d{4}-\d{3}[\dX]`}}
+	violations := Check(rules, nil, map[string]interface{}{"ORCID": "0000-0002-1825-009X"})
+	if len(violations) != 0 {
+		t.Fatal(violations)
+	}
+	violations = Check(rules, nil, map[string]interface{}{"ORCID": "0000-0002-1825-009"})
+	if len(violations) != 1 {
+		t.Fatal("a truncated ORCID should be rejected")
+	}
+}
+
+func TestUncovered(t *testing.T) {
+	f, err := Load("../cmd/phd_profile/phdPolicy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldJSON, newJSON := readProfiles(t)
+	oldRecord, err := Decode([]byte(oldJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newRecord, err := Decode([]byte(newJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{"status", "programYear", "duration", "studentID", "publications"}
+	cases := []struct {
+		approving []string
+		uncovered string
+	}{
+		{[]string{"registrar", "student"}, ""},
+		{[]string{"registrar"}, "publications"},
+		{[]string{"student"}, "status duration"},
+	}
+	for _, c := range cases {
+		uncovered, err := f.Uncovered(paths, c.approving, oldRecord, newRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(uncovered, " ") != c.uncovered {
+			t.Errorf("%v: expected %q to be uncovered, got %v", c.approving, c.uncovered, uncovered)
+		}
+	}
+	if _, err := f.Uncovered(paths, []string{"dean"}, oldRecord, newRecord); err == nil {
+		t.Fatal("expected an unknown role to be rejected")
+	}
+	f.Roles = append(f.Roles, Role{Name: "student", Fields: []string{"programYear"}})
+	if err := f.Validate(); err == nil {
+		t.Fatal("expected a duplicated role to be rejected")
+	}
+}